@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+
+	"9fans.net/go/draw"
+	"github.com/mjl-/duit"
+)
+
+// canvasUI shows custom graphics, like diagrams and charts, drawn by a render function.
+// Dragging with button 1 pans, the scroll wheel zooms if zoom is set.
+type canvasUI struct {
+	render func(img *draw.Image, r image.Rectangle, offset image.Point) // draws the graphics in r, shifted by offset
+	zoom   func(delta int)                                              // called with 1 for zooming in, -1 for zooming out
+
+	offset image.Point
+	size   image.Point
+	m      draw.Mouse
+}
+
+var _ duit.UI = &canvasUI{}
+
+func (ui *canvasUI) Layout(dui *duit.DUI, self *duit.Kid, sizeAvail image.Point, force bool) {
+	ui.size = sizeAvail
+	self.R = image.Rectangle{Max: sizeAvail}
+}
+
+func (ui *canvasUI) Draw(dui *duit.DUI, self *duit.Kid, img *draw.Image, orig image.Point, m draw.Mouse, force bool) {
+	r := self.R.Add(orig)
+	clipr := img.Clipr
+	img.ReplClipr(img.Repl, r.Intersect(clipr))
+	defer img.ReplClipr(img.Repl, clipr)
+	img.Draw(r, colorImage(draw.White), nil, image.ZP)
+	if ui.render != nil {
+		ui.render(img, r, ui.offset)
+	}
+}
+
+func (ui *canvasUI) Mouse(dui *duit.DUI, self *duit.Kid, m draw.Mouse, origM draw.Mouse, orig image.Point) (r duit.Result) {
+	switch {
+	case m.Buttons == duit.Button1 && ui.m.Buttons == duit.Button1:
+		ui.offset = ui.offset.Add(m.Point.Sub(ui.m.Point))
+		self.Draw = duit.Dirty
+		r.Consumed = true
+	case m.Buttons&(duit.Button4|duit.Button5) != 0 && ui.m.Buttons&(duit.Button4|duit.Button5) == 0 && ui.zoom != nil:
+		if m.Buttons&duit.Button4 != 0 {
+			ui.zoom(1)
+		} else {
+			ui.zoom(-1)
+		}
+		self.Draw = duit.Dirty
+		r.Consumed = true
+	}
+	ui.m = m
+	return
+}
+
+func (ui *canvasUI) Key(dui *duit.DUI, self *duit.Kid, k rune, m draw.Mouse, orig image.Point) (r duit.Result) {
+	return
+}
+
+func (ui *canvasUI) FirstFocus(dui *duit.DUI, self *duit.Kid) *image.Point {
+	return nil
+}
+
+func (ui *canvasUI) Focus(dui *duit.DUI, self *duit.Kid, o duit.UI) *image.Point {
+	if ui != o {
+		return nil
+	}
+	return &image.ZP
+}
+
+func (ui *canvasUI) Mark(self *duit.Kid, o duit.UI, forLayout bool) (marked bool) {
+	return self.Mark(o, forLayout)
+}
+
+func (ui *canvasUI) Print(self *duit.Kid, indent int) {
+	duit.PrintUI("canvasUI", self, indent)
+}
+
+var colorImages = map[draw.Color]*draw.Image{}
+
+// colorImage returns a replicated image of color c, for drawing with.
+// Called from main loop.
+func colorImage(c draw.Color) *draw.Image {
+	img, ok := colorImages[c]
+	if !ok {
+		var err error
+		img, err = dui.Display.AllocImage(image.Rect(0, 0, 1, 1), draw.ABGR32, true, c)
+		check(err, "allocating color")
+		colorImages[c] = img
+	}
+	return img
+}
+
+// drawArrow draws a line from p0 to p1, with an arrow head at p1.
+func drawArrow(img *draw.Image, p0, p1 image.Point, color *draw.Image) {
+	img.Line(p0, p1, 0, 0, 0, color, image.ZP)
+	d := p1.Sub(p0)
+	n := abs(d.X) + abs(d.Y)
+	if n == 0 {
+		return
+	}
+	// a head of about 8 pixels long, 3 wide on each side
+	back := image.Pt(d.X*8/n, d.Y*8/n)
+	side := image.Pt(-d.Y*3/n, d.X*3/n)
+	base := p1.Sub(back)
+	img.FillPoly([]image.Point{p1, base.Add(side), base.Sub(side)}, 0, 0, 0, color, image.ZP)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// savePNG renders graphics of size into an offscreen image and writes it as PNG file to path.
+// Called from main loop.
+func savePNG(path string, size image.Point, render func(img *draw.Image, r image.Rectangle)) error {
+	if size.X <= 0 || size.Y <= 0 || size.X > 16000 || size.Y > 16000 {
+		return fmt.Errorf("bad image size %dx%d", size.X, size.Y)
+	}
+	r := image.Rectangle{Max: size}
+	img, err := dui.Display.AllocImage(r, draw.ABGR32, false, draw.White)
+	if err != nil {
+		return fmt.Errorf("allocating image: %s", err)
+	}
+	defer img.Free()
+	render(img, r)
+
+	// the draw protocol limits the bytes per line that can be read at once, so we read strips
+	rgba := image.NewRGBA(r)
+	const strip = 1024
+	for x := 0; x < size.X; x += strip {
+		w := size.X - x
+		if w > strip {
+			w = strip
+		}
+		buf := make([]byte, w*4*size.Y)
+		_, err := img.Unload(image.Rect(x, 0, x+w, size.Y), buf)
+		if err != nil {
+			return fmt.Errorf("reading image: %s", err)
+		}
+		for y := 0; y < size.Y; y++ {
+			copy(rgba.Pix[y*rgba.Stride+x*4:], buf[y*w*4:(y+1)*w*4])
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = png.Encode(f, rgba)
+	if err2 := f.Close(); err == nil {
+		err = err2
+	}
+	return err
+}
@@ -0,0 +1,333 @@
+package main
+
+import (
+	"image"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"9fans.net/go/draw"
+)
+
+var chartKinds = []string{"bar", "line", "scatter"}
+
+// colors of the series in a chart
+var chartColors = []draw.Color{0x1F77B4FF, 0xFF7F0EFF, 0x2CA02CFF, 0xD62728FF, 0x9467BDFF, 0x8C564BFF, 0xE377C2FF, 0x7F7F7FFF}
+
+const (
+	chartGridColor draw.Color = 0xE0E0E0FF
+	chartTicks                = 6  // approximate number of ticks on an axis
+	chartLabelMax             = 16 // characters of category labels
+)
+
+// chartSeries is a column plotted on the y axis, with a value per point.
+type chartSeries struct {
+	name  string
+	ys    []float64
+	valid []bool // false for points without numeric value
+}
+
+// chart is a plot of one or more columns of a result against another column.
+type chart struct {
+	kind    string // bar, line or scatter
+	xName   string
+	xMode   string    // number, time or category
+	xs      []float64 // per point, index for categories, unix seconds for times
+	xLabels []string  // per point, text of the x value
+	series  []chartSeries
+}
+
+// numericColumn returns whether all non-NULL values of column col are numeric, and there is at least one.
+func numericColumn(columns []resultColumn, values [][]interface{}, col int) bool {
+	n := 0
+	for _, row := range values {
+		if row[col] == nil {
+			continue
+		}
+		if _, ok := numericValue(columns[col].kind, row[col]); !ok {
+			return false
+		}
+		n++
+	}
+	return n > 0
+}
+
+// newChart returns a chart of kind for rows, indices into values and texts, with column xCol on the x axis and yCols on the y axis.
+// For line and scatter charts, x values are plotted by value if they are all numbers or all times.
+func newChart(kind string, columns []resultColumn, values [][]interface{}, texts [][]string, rows []int, xCol int, yCols []int) *chart {
+	c := &chart{kind: kind, xName: columns[xCol].name, xMode: "category"}
+	if kind != "bar" {
+		times, numbers := true, true
+		for _, i := range rows {
+			v := values[i][xCol]
+			if v == nil {
+				continue
+			}
+			if _, ok := v.(time.Time); !ok {
+				times = false
+			}
+			if _, ok := numericValue(columns[xCol].kind, v); !ok {
+				numbers = false
+			}
+		}
+		switch {
+		case numbers:
+			c.xMode = "number"
+		case times:
+			c.xMode = "time"
+		}
+	}
+
+	var points []int
+	for _, i := range rows {
+		v := values[i][xCol]
+		switch c.xMode {
+		case "category":
+			c.xs = append(c.xs, float64(len(c.xs)))
+		case "time":
+			if v == nil {
+				continue
+			}
+			t := v.(time.Time)
+			c.xs = append(c.xs, float64(t.UnixNano())/1e9)
+		case "number":
+			if v == nil {
+				continue
+			}
+			r, _ := numericValue(columns[xCol].kind, v)
+			f, _ := r.Float64()
+			c.xs = append(c.xs, f)
+		}
+		c.xLabels = append(c.xLabels, texts[i][xCol])
+		points = append(points, i)
+	}
+	for _, col := range yCols {
+		s := chartSeries{name: columns[col].name}
+		for _, i := range points {
+			var f float64
+			r, ok := numericValue(columns[col].kind, values[i][col])
+			if ok {
+				f, _ = r.Float64()
+			}
+			s.ys = append(s.ys, f)
+			s.valid = append(s.valid, ok)
+		}
+		c.series = append(c.series, s)
+	}
+	return c
+}
+
+// niceTicks returns about n round values covering min to max.
+func niceTicks(min, max float64, n int) []float64 {
+	if max <= min {
+		// widen relative to the value, for large values close to each other
+		d := math.Max(math.Abs(min)*1e-9, 1)
+		min, max = min-d, min+d
+	}
+	raw := (max - min) / float64(n)
+	exp := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := 10 * exp
+	for _, f := range []float64{1, 2, 5} {
+		if raw <= f*exp {
+			step = f * exp
+			break
+		}
+	}
+	// by index, adding step to large values may not change them
+	start := math.Floor(min/step) * step
+	ticks := []float64{start}
+	for i := 1; i <= 4*n && ticks[len(ticks)-1] < max; i++ {
+		ticks = append(ticks, start+float64(i)*step)
+	}
+	return ticks
+}
+
+// formatTick returns the label for tick v, on an axis with steps of step.
+func (c *chart) formatTick(v, step float64, xAxis bool) string {
+	if !xAxis || c.xMode != "time" {
+		return strconv.FormatFloat(v, 'g', 8, 64)
+	}
+	t := time.Unix(0, int64(v*1e9))
+	switch {
+	case step >= 24*3600:
+		return t.Format("2006-01-02")
+	case step >= 60:
+		return t.Format("01-02 15:04")
+	}
+	return t.Format("15:04:05")
+}
+
+// truncateLabel shortens s to chartLabelMax characters.
+func truncateLabel(s string) string {
+	r := []rune(s)
+	if len(r) > chartLabelMax {
+		return string(r[:chartLabelMax-3]) + "..."
+	}
+	return s
+}
+
+// draw draws the chart with axes, ticks and legend in r.
+// Called from main loop.
+func (c *chart) draw(img *draw.Image, r image.Rectangle, font *draw.Font) {
+	black := colorImage(draw.Black)
+	grid := colorImage(chartGridColor)
+	if len(c.xs) == 0 || len(c.series) == 0 {
+		img.String(r.Min.Add(image.Pt(10, 10)), black, image.ZP, font, "nothing to plot")
+		return
+	}
+
+	// value ranges, bars start at 0
+	ymin, ymax := math.Inf(1), math.Inf(-1)
+	if c.kind == "bar" {
+		ymin, ymax = 0, 0
+	}
+	for _, s := range c.series {
+		for i, y := range s.ys {
+			if s.valid[i] {
+				ymin = math.Min(ymin, y)
+				ymax = math.Max(ymax, y)
+			}
+		}
+	}
+	if math.IsInf(ymin, 0) {
+		ymin, ymax = 0, 1
+	}
+	yticks := niceTicks(ymin, ymax, chartTicks)
+	ymin, ymax = yticks[0], yticks[len(yticks)-1]
+	ystep := yticks[1] - yticks[0]
+
+	xmin, xmax := c.xs[0], c.xs[0]
+	for _, x := range c.xs {
+		xmin = math.Min(xmin, x)
+		xmax = math.Max(xmax, x)
+	}
+	var xticks []float64
+	var xstep float64
+	if c.xMode == "category" {
+		xmin, xmax = -0.5, float64(len(c.xs))-0.5
+	} else {
+		xticks = niceTicks(xmin, xmax, chartTicks)
+		xmin, xmax = xticks[0], xticks[len(xticks)-1]
+		xstep = xticks[1] - xticks[0]
+	}
+
+	// plot area, leaving room for tick labels, the x axis name and the legend
+	left := 0
+	for _, v := range yticks {
+		if w := font.StringWidth(c.formatTick(v, ystep, false)); w > left {
+			left = w
+		}
+	}
+	plot := image.Rect(r.Min.X+left+16, r.Min.Y+font.Height+16, r.Max.X-24, r.Max.Y-2*font.Height-16)
+	if plot.Dx() < 20 || plot.Dy() < 20 {
+		return
+	}
+	px := func(x float64) int {
+		return plot.Min.X + int(math.Round((x-xmin)/(xmax-xmin)*float64(plot.Dx())))
+	}
+	py := func(y float64) int {
+		return plot.Max.Y - int(math.Round((y-ymin)/(ymax-ymin)*float64(plot.Dy())))
+	}
+
+	// grid lines and tick labels
+	for _, v := range yticks {
+		y := py(v)
+		img.Line(image.Pt(plot.Min.X, y), image.Pt(plot.Max.X, y), 0, 0, 0, grid, image.ZP)
+		s := c.formatTick(v, ystep, false)
+		img.String(image.Pt(plot.Min.X-6-font.StringWidth(s), y-font.Height/2), black, image.ZP, font, s)
+	}
+	labelY := plot.Max.Y + 6
+	if c.xMode == "category" {
+		// skip labels that would overlap
+		width := font.StringWidth("x") * (chartLabelMax + 2)
+		every := 1
+		if n := plot.Dx() / width; n > 0 && len(c.xs) > n {
+			every = (len(c.xs) + n - 1) / n
+		}
+		for i, x := range c.xs {
+			if i%every != 0 {
+				continue
+			}
+			s := truncateLabel(c.xLabels[i])
+			img.String(image.Pt(px(x)-font.StringWidth(s)/2, labelY), black, image.ZP, font, s)
+		}
+	} else {
+		for _, v := range xticks {
+			x := px(v)
+			img.Line(image.Pt(x, plot.Min.Y), image.Pt(x, plot.Max.Y), 0, 0, 0, grid, image.ZP)
+			s := c.formatTick(v, xstep, true)
+			img.String(image.Pt(x-font.StringWidth(s)/2, labelY), black, image.ZP, font, s)
+		}
+	}
+	img.String(image.Pt((plot.Min.X+plot.Max.X-bold.StringWidth(c.xName))/2, labelY+font.Height+4), black, image.ZP, bold, c.xName)
+
+	// axes, the x axis at 0 if it is in range
+	axisY := plot.Max.Y
+	if ymin < 0 && ymax > 0 {
+		axisY = py(0)
+	}
+	img.Line(image.Pt(plot.Min.X, plot.Min.Y), image.Pt(plot.Min.X, plot.Max.Y), 0, 0, 0, black, image.ZP)
+	img.Line(image.Pt(plot.Min.X, axisY), image.Pt(plot.Max.X, axisY), 0, 0, 0, black, image.ZP)
+
+	// the data
+	clipr := img.Clipr
+	img.ReplClipr(img.Repl, plot.Inset(-4).Intersect(clipr))
+	for si, s := range c.series {
+		color := colorImage(chartColors[si%len(chartColors)])
+		switch c.kind {
+		case "bar":
+			group := float64(plot.Dx()) / float64(len(c.xs))
+			width := group * 0.8 / float64(len(c.series))
+			for i, x := range c.xs {
+				if !s.valid[i] {
+					continue
+				}
+				x0 := px(x) - int(group*0.4) + int(float64(si)*width)
+				br := image.Rect(x0, py(s.ys[i]), x0+int(math.Max(1, width)), py(0)).Canon()
+				img.Draw(br, color, nil, image.ZP)
+			}
+		case "line":
+			order := make([]int, len(c.xs))
+			for i := range order {
+				order[i] = i
+			}
+			sort.SliceStable(order, func(i, j int) bool {
+				return c.xs[order[i]] < c.xs[order[j]]
+			})
+			var pts []image.Point
+			for _, i := range order {
+				if s.valid[i] {
+					pts = append(pts, image.Pt(px(c.xs[i]), py(s.ys[i])))
+				}
+			}
+			if len(pts) > 1 {
+				img.Poly(pts, 0, 0, 0, color, image.ZP)
+			}
+			if len(pts) < plot.Dx()/8 {
+				for _, p := range pts {
+					img.FillEllipse(p, 2, 2, 0, color, image.ZP)
+				}
+			}
+		case "scatter":
+			for i, x := range c.xs {
+				if s.valid[i] {
+					img.FillEllipse(image.Pt(px(x), py(s.ys[i])), 3, 3, 0, color, image.ZP)
+				}
+			}
+		}
+	}
+	img.ReplClipr(img.Repl, clipr)
+
+	// legend, right-aligned above the plot
+	x := plot.Max.X
+	for si := len(c.series) - 1; si >= 0; si-- {
+		name := c.series[si].name
+		x -= font.StringWidth(name)
+		img.String(image.Pt(x, r.Min.Y+6), black, image.ZP, font, name)
+		x -= font.Height + 4
+		box := image.Rect(x, r.Min.Y+6, x+font.Height, r.Min.Y+6+font.Height).Inset(2)
+		img.Draw(box, colorImage(chartColors[si%len(chartColors)]), nil, image.ZP)
+		x -= 12
+	}
+}
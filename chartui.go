@@ -0,0 +1,201 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path"
+
+	"9fans.net/go/draw"
+	"github.com/mjl-/duit"
+)
+
+// chartUI plots columns of the rows of a resultUI, as filtered and sorted in its grid.
+type chartUI struct {
+	resultUI *resultUI
+	kind     *duit.Buttongroup
+	xList    *duit.Gridlist // column for the x axis
+	yList    *duit.Gridlist // numeric columns for the y axis
+	path     *duit.Field
+	message  *duit.Label
+	canvas   *canvasUI
+
+	chart *chart
+
+	duit.Box
+}
+
+func newChartUI(rUI *resultUI) (ui *chartUI) {
+	ui = &chartUI{resultUI: rUI}
+	changed := func(index int) (e duit.Event) {
+		ui.plot()
+		return
+	}
+	ui.kind = &duit.Buttongroup{
+		Texts: chartKinds,
+		Changed: func(index int) (e duit.Event) {
+			ui.plot()
+			return
+		},
+	}
+	ui.xList = &duit.Gridlist{
+		Header:  &duit.Gridrow{Values: []string{"x axis"}},
+		Striped: true,
+		Padding: duit.SpaceXY(4, 2),
+		Changed: changed,
+	}
+	ui.yList = &duit.Gridlist{
+		Header:   &duit.Gridrow{Values: []string{"y axis"}},
+		Multiple: true,
+		Striped:  true,
+		Padding:  duit.SpaceXY(4, 2),
+		Changed:  changed,
+	}
+	ui.path = &duit.Field{Text: duit.AppDataDir("duitsql") + "/chart.png"}
+	ui.message = &duit.Label{}
+	ui.canvas = &canvasUI{
+		render: func(img *draw.Image, r image.Rectangle, offset image.Point) {
+			if ui.chart != nil {
+				ui.chart.draw(img, r, dui.Display.DefaultFont)
+			}
+		},
+	}
+	save := &duit.Button{
+		Text: "save png",
+		Click: func() (e duit.Event) {
+			ui.savePNG()
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(
+		&duit.Split{
+			Gutter:     1,
+			Background: dui.Gutter,
+			Split: func(width int) []int {
+				first := dui.Scale(200)
+				if first > width/3 {
+					first = width / 3
+				}
+				return []int{first, width - first}
+			},
+			Kids: duit.NewKids(
+				&duit.Box{Kids: duit.NewKids(ui.xList, ui.yList)},
+				&duit.Box{
+					Kids: duit.NewKids(
+						&duit.Box{
+							Padding: duit.SpaceXY(4, 2),
+							Margin:  image.Pt(4, 2),
+							Valign:  duit.ValignMiddle,
+							Kids: duit.NewKids(
+								ui.kind,
+								&duit.Box{Width: 300, Kids: duit.NewKids(ui.path)},
+								save,
+								ui.message,
+							),
+						},
+						ui.canvas,
+					),
+				},
+			),
+		},
+	)
+	return
+}
+
+func (ui *chartUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+// init fills the column lists, keeping the selected columns if they are still present, and plots.
+// By default the first column is plotted against the first other numeric column.
+// Called from main loop.
+func (ui *chartUI) init() {
+	rUI := ui.resultUI
+	selected := func(l *duit.Gridlist) map[string]bool {
+		m := map[string]bool{}
+		for _, row := range l.Rows {
+			if row.Selected {
+				m[row.Values[0]] = true
+			}
+		}
+		return m
+	}
+	xSel, ySel := selected(ui.xList), selected(ui.yList)
+	first := len(ui.xList.Rows) == 0
+
+	ui.xList.Rows = nil
+	ui.yList.Rows = nil
+	for i, c := range rUI.columns {
+		ui.xList.Rows = append(ui.xList.Rows, &duit.Gridrow{
+			Selected: xSel[c.name] || first && i == 0,
+			Values:   []string{c.name},
+			Value:    i,
+		})
+		if numericColumn(rUI.columns, rUI.values, i) {
+			ui.yList.Rows = append(ui.yList.Rows, &duit.Gridrow{
+				Selected: ySel[c.name],
+				Values:   []string{c.name},
+				Value:    i,
+			})
+		}
+	}
+	if first {
+		for _, row := range ui.yList.Rows {
+			if row.Value.(int) != 0 {
+				row.Selected = true
+				break
+			}
+		}
+	}
+	ui.plot()
+}
+
+// plot makes a chart of the selected columns.
+// Called from main loop.
+func (ui *chartUI) plot() {
+	defer ui.layout()
+	rUI := ui.resultUI
+	ui.chart = nil
+	ui.message.Text = ""
+	xSel := ui.xList.Selected()
+	ySel := ui.yList.Selected()
+	switch {
+	case len(xSel) != 1:
+		ui.message.Text = "select a column for the x axis"
+	case len(ySel) == 0:
+		ui.message.Text = "select numeric columns for the y axis"
+	default:
+		var yCols []int
+		for _, i := range ySel {
+			yCols = append(yCols, ui.yList.Rows[i].Value.(int))
+		}
+		var rows []int
+		for _, row := range rUI.grid.Rows {
+			rows = append(rows, row.Value.(int))
+		}
+		kind := chartKinds[ui.kind.Selected]
+		ui.chart = newChart(kind, rUI.columns, rUI.values, rUI.texts, rows, ui.xList.Rows[xSel[0]].Value.(int), yCols)
+		ui.message.Text = fmt.Sprintf("%d points", len(ui.chart.xs))
+	}
+	dui.MarkDraw(ui.canvas)
+}
+
+// savePNG writes the chart, at its size on screen, as PNG file.
+// Called from main loop.
+func (ui *chartUI) savePNG() {
+	defer ui.layout()
+	if ui.chart == nil {
+		ui.message.Text = "no chart"
+		return
+	}
+	p := ui.path.Text
+	os.MkdirAll(path.Dir(p), 0777)
+	err := savePNG(p, ui.canvas.size, func(img *draw.Image, r image.Rectangle) {
+		ui.chart.draw(img, r, dui.Display.DefaultFont)
+	})
+	if err != nil {
+		ui.message.Text = fmt.Sprintf("error: %s", err)
+	} else {
+		ui.message.Text = "saved " + p
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"github.com/mjl-/duit"
+)
+
+// columnsUI lets the user hide, reorder and sort the columns of a resultUI.
+type columnsUI struct {
+	resultUI *resultUI
+	order    []int  // all column indices, in display order
+	hidden   []bool // per column index
+
+	duit.Box
+}
+
+func newColumnsUI(rUI *resultUI) *columnsUI {
+	ui := &columnsUI{
+		resultUI: rUI,
+		order:    make([]int, len(rUI.columns)),
+		hidden:   make([]bool, len(rUI.columns)),
+	}
+	for i := range ui.order {
+		ui.order[i] = i
+	}
+	return ui
+}
+
+// apply sets the displayed columns of the resultUI and redraws.
+// Called from main loop.
+func (ui *columnsUI) apply() {
+	rUI := ui.resultUI
+	rUI.display = []int{}
+	for _, col := range ui.order {
+		if !ui.hidden[col] {
+			rUI.display = append(rUI.display, col)
+		}
+	}
+	rUI.refresh()
+	ui.init()
+}
+
+// move moves the column at position i in the order by delta positions.
+func (ui *columnsUI) move(i, delta int) {
+	j := i + delta
+	if j < 0 || j >= len(ui.order) {
+		return
+	}
+	ui.order[i], ui.order[j] = ui.order[j], ui.order[i]
+	ui.apply()
+}
+
+// init (re)creates the grid with a line per column.
+// Called from main loop.
+func (ui *columnsUI) init() {
+	rUI := ui.resultUI
+	kids := []duit.UI{
+		&duit.Label{Font: bold, Text: "show"},
+		&duit.Label{Font: bold, Text: "column"},
+		&duit.Label{Font: bold, Text: "type"},
+		&duit.Label{Font: bold, Text: "order"},
+		&duit.Label{Font: bold, Text: "sort"},
+	}
+	for i, col := range ui.order {
+		i, col := i, col
+		show := &duit.Checkbox{
+			Checked: !ui.hidden[col],
+			Changed: func() (e duit.Event) {
+				ui.hidden[col] = !ui.hidden[col]
+				ui.apply()
+				return
+			},
+		}
+		sortText := "sort"
+		if rUI.sortCol == col {
+			if rUI.sortDesc {
+				sortText = "descending"
+			} else {
+				sortText = "ascending"
+			}
+		}
+		sortButton := &duit.Button{
+			Text: sortText,
+			Click: func() (e duit.Event) {
+				// cycle through ascending, descending and unsorted
+				switch {
+				case rUI.sortCol != col:
+					rUI.sortCol = col
+					rUI.sortDesc = false
+				case !rUI.sortDesc:
+					rUI.sortDesc = true
+				default:
+					rUI.sortCol = -1
+				}
+				ui.apply()
+				return
+			},
+		}
+		kids = append(kids,
+			show,
+			label(rUI.columns[col].name),
+			label(rUI.columns[col].dbType),
+			&duit.Box{
+				Kids: duit.NewKids(
+					&duit.Button{
+						Text: "up",
+						Click: func() (e duit.Event) {
+							ui.move(i, -1)
+							return
+						},
+					},
+					&duit.Button{
+						Text: "down",
+						Click: func() (e duit.Event) {
+							ui.move(i, 1)
+							return
+						},
+					},
+				),
+			},
+			sortButton,
+		)
+	}
+	ui.Box.Kids = duit.NewKids(
+		&duit.Box{
+			Padding: duit.SpaceXY(4, 2),
+			Kids: duit.NewKids(
+				&duit.Grid{
+					Columns: 5,
+					Padding: duit.NSpaceXY(5, 4, 1),
+					Valign:  []duit.Valign{duit.ValignMiddle, duit.ValignMiddle, duit.ValignMiddle, duit.ValignMiddle, duit.ValignMiddle},
+					Kids:    duit.NewKids(kids...),
+				},
+			),
+		},
+	)
+	dui.MarkLayout(ui)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// compareValues compares two raw values of a column of kind, returning -1, 0 or 1.
+// NULLs sort first, then numbers. Numbers and times are compared by value, not by their text.
+func compareValues(kind valueKind, a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+	// numbers sort before other values, comparing a number with text would not give a consistent order
+	ra, aok := numericValue(kind, a)
+	rb, bok := numericValue(kind, b)
+	switch {
+	case aok && bok:
+		return ra.Cmp(rb)
+	case aok:
+		return -1
+	case bok:
+		return 1
+	}
+	switch va := a.(type) {
+	case time.Time:
+		if vb, ok := b.(time.Time); ok {
+			switch {
+			case va.Before(vb):
+				return -1
+			case va.After(vb):
+				return 1
+			}
+			return 0
+		}
+	case bool:
+		if vb, ok := b.(bool); ok {
+			switch {
+			case va == vb:
+				return 0
+			case !va:
+				return -1
+			}
+			return 1
+		}
+	case []byte:
+		if vb, ok := b.([]byte); ok {
+			return bytes.Compare(va, vb)
+		}
+	}
+	return strings.Compare(fmt.Sprintf("%s", textValue(a)), fmt.Sprintf("%s", textValue(b)))
+}
+
+// numericValue returns v as number if it is one, either as Go number or as text of a numeric column.
+func numericValue(kind valueKind, v interface{}) (*big.Rat, bool) {
+	switch vv := v.(type) {
+	case int64:
+		return new(big.Rat).SetInt64(vv), true
+	case float64:
+		r := new(big.Rat)
+		return r, r.SetFloat64(vv) != nil
+	case float32:
+		r := new(big.Rat)
+		return r, r.SetFloat64(float64(vv)) != nil
+	case []byte:
+		if kind == kindNumeric || kind == kindOther {
+			return new(big.Rat).SetString(string(vv))
+		}
+	case string:
+		if kind == kindNumeric {
+			return new(big.Rat).SetString(vv)
+		}
+	}
+	return nil, false
+}
+
+// textValue returns v as string or []byte for text comparison.
+func textValue(v interface{}) interface{} {
+	switch v.(type) {
+	case string, []byte:
+		return v
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// sameValue returns whether value a of kind ka, and value b of kind kb, possibly read from different
+// types of databases, are equal. Numbers are compared by value, booleans as 0 or 1, times by instant or text.
+func sameValue(ka valueKind, a interface{}, kb valueKind, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if v, ok := a.(bool); ok {
+		a, ka = boolInt(v), kindNumeric
+	}
+	if v, ok := b.(bool); ok {
+		b, kb = boolInt(v), kindNumeric
+	}
+	if ra, ok := numericValue(ka, a); ok {
+		if rb, ok := numericValue(kb, b); ok {
+			return ra.Cmp(rb) == 0
+		}
+	}
+	ta, aTime := a.(time.Time)
+	tb, bTime := b.(time.Time)
+	switch {
+	case aTime && bTime:
+		return ta.Equal(tb)
+	case aTime:
+		return timeText(ta) == timeText(parseTimeText(fmt.Sprintf("%s", textValue(b)), ta.Location()))
+	case bTime:
+		return timeText(tb) == timeText(parseTimeText(fmt.Sprintf("%s", textValue(a)), tb.Location()))
+	}
+	if ba, ok := a.([]byte); ok {
+		if bb, ok := b.([]byte); ok {
+			return bytes.Equal(ba, bb)
+		}
+	}
+	return fmt.Sprintf("%s", textValue(a)) == fmt.Sprintf("%s", textValue(b))
+}
+
+func boolInt(v bool) int64 {
+	if v {
+		return 1
+	}
+	return 0
+}
+
+// timeText returns t without zone, for comparing with times from databases that return times as text.
+func timeText(t time.Time) string {
+	return t.Format("2006-01-02 15:04:05.999999999")
+}
+
+// parseTimeText parses s as time in loc, returning the zero time if it cannot be parsed.
+func parseTimeText(s string, loc *time.Location) time.Time {
+	for _, layout := range importTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
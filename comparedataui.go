@@ -0,0 +1,528 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/mjl-/duit"
+)
+
+const compareRowLimit = 100000 // maximum number of rows read per side
+
+var compareFilters = []string{"all", "left only", "right only", "changed", "duplicate key"}
+
+// compareSide is a table or query in a database, one of the two sides of a compare.
+type compareSide struct {
+	config connectionConfig
+	dbName string
+	source string // table name, or a query
+}
+
+// isQuery returns whether the source is a query instead of a table name.
+func (s compareSide) isQuery() bool {
+	t := strings.ToLower(strings.TrimSpace(s.source))
+	return strings.HasPrefix(t, "select ") || strings.HasPrefix(t, "with ") || strings.ContainsAny(t, " \n\t")
+}
+
+// compareData holds the rows of a side of a compare, with the values normalized for comparing.
+type compareData struct {
+	columns []string
+	kinds   []valueKind
+	rows    [][]interface{}
+	more    bool // whether rows were left out because of compareRowLimit
+}
+
+// compareRow is a difference between the sides, for a key.
+type compareRow struct {
+	status      string        // left only, right only, changed, duplicate key
+	left, right []interface{} // values of the common columns, nil if the row is not on that side
+	changed     map[int]bool  // indices of common columns that differ
+}
+
+// compareDataUI compares the rows of tables or queries in two databases, matching rows by key columns,
+// and generates a script with statements to make the right side equal to the left side.
+type compareDataUI struct {
+	connUI *connUI
+
+	leftDB      *duit.Field
+	leftSource  *duit.Field
+	rightConn   *duit.Field
+	rightDB     *duit.Field
+	rightSource *duit.Field
+	keys        *duit.Field // key columns, comma-separated, primary key if empty
+	filter      *duit.Buttongroup
+	message     *duit.Label
+	grid        *duit.Gridlist
+	scriptBox   *duit.Box
+
+	columns []string // common columns, key columns first
+	results []compareRow
+	cancel  context.CancelFunc // cancels the running compare, nil if none
+	gen     int                // incremented for each compare, results of older compares are ignored
+
+	duit.Box
+}
+
+func newCompareDataUI(cUI *connUI) (ui *compareDataUI) {
+	ui = &compareDataUI{connUI: cUI}
+	ui.leftDB = &duit.Field{Placeholder: "database..."}
+	ui.leftSource = &duit.Field{Placeholder: "table or query..."}
+	ui.rightConn = &duit.Field{Text: cUI.config.Name}
+	ui.rightDB = &duit.Field{Placeholder: "database..."}
+	ui.rightSource = &duit.Field{Placeholder: "table or query, same as left if empty"}
+	ui.keys = &duit.Field{Placeholder: "primary key"}
+	ui.message = &duit.Label{}
+	ui.filter = &duit.Buttongroup{
+		Texts: compareFilters,
+		Changed: func(index int) (e duit.Event) {
+			ui.showResults()
+			return
+		},
+	}
+	ui.grid = &duit.Gridlist{
+		Striped: true,
+		Padding: duit.SpaceXY(4, 2),
+	}
+	ui.scriptBox = &duit.Box{}
+
+	compare := &duit.Button{
+		Text:     "compare",
+		Colorset: &dui.Primary,
+		Click: func() (e duit.Event) {
+			ui.compare()
+			return
+		},
+	}
+	cancel := &duit.Button{
+		Text: "cancel",
+		Click: func() (e duit.Event) {
+			if ui.cancel != nil {
+				ui.cancel()
+			}
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(
+		toolbar(
+			label("left: database"),
+			&duit.Box{Width: 150, Kids: duit.NewKids(ui.leftDB)},
+			&duit.Box{Width: 350, Kids: duit.NewKids(ui.leftSource)},
+		),
+		toolbar(
+			label("right: connection"),
+			&duit.Box{Width: 150, Kids: duit.NewKids(ui.rightConn)},
+			label("database"),
+			&duit.Box{Width: 150, Kids: duit.NewKids(ui.rightDB)},
+			&duit.Box{Width: 350, Kids: duit.NewKids(ui.rightSource)},
+		),
+		toolbar(
+			label("key columns"),
+			&duit.Box{Width: 200, Kids: duit.NewKids(ui.keys)},
+			compare,
+			cancel,
+			ui.message,
+		),
+		&duit.Tabs{
+			Buttongroup: &duit.Buttongroup{
+				Texts: []string{"Differences", "Sync script"},
+			},
+			UIs: []duit.UI{
+				&duit.Box{
+					Kids: duit.NewKids(
+						toolbar(label("show"), ui.filter),
+						duit.NewScroll(ui.grid),
+					),
+				},
+				ui.scriptBox,
+			},
+		},
+	)
+	return
+}
+
+func (ui *compareDataUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+// compare starts reading both sides and comparing them.
+// Called from main loop.
+func (ui *compareDataUI) compare() {
+	defer ui.layout()
+	if ui.cancel != nil {
+		ui.cancel()
+		ui.cancel = nil
+	}
+	left := compareSide{ui.connUI.config, ui.leftDB.Text, ui.leftSource.Text}
+	rightConfig, err := findConnection(ui.rightConn.Text)
+	if err != nil {
+		ui.message.Text = fmt.Sprintf("error: %s", err)
+		return
+	}
+	right := compareSide{rightConfig, ui.rightDB.Text, ui.rightSource.Text}
+	if right.dbName == "" {
+		right.dbName = left.dbName
+	}
+	if right.source == "" {
+		right.source = left.source
+	}
+	if left.dbName == "" || left.source == "" {
+		ui.message.Text = "error: left database and table or query are required"
+		return
+	}
+	var keys []string
+	for _, k := range strings.Split(ui.keys.Text, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancel = cancel
+	ui.gen++
+	gen := ui.gen
+	ui.message.Text = "reading rows..."
+	go func() {
+		defer cancel()
+		lcheck, handle := errorHandler(func(err error) {
+			dui.Call <- func() {
+				if ui.gen != gen {
+					return
+				}
+				ui.cancel = nil
+				ui.message.Text = fmt.Sprintf("error: %s", err)
+				ui.layout()
+			}
+		})
+		defer handle()
+
+		leftDB, err := sql.Open(left.config.Type, left.config.connectionString(left.dbName))
+		lcheck(err, "connecting to left database")
+		defer leftDB.Close()
+		rightDB, err := sql.Open(right.config.Type, right.config.connectionString(right.dbName))
+		lcheck(err, "connecting to right database")
+		defer rightDB.Close()
+
+		if len(keys) == 0 {
+			for _, s := range []struct {
+				side compareSide
+				db   *sql.DB
+			}{{left, leftDB}, {right, rightDB}} {
+				if len(keys) == 0 && !s.side.isQuery() {
+					_, keys, err = loadTableColumns(ctx, s.db, s.side.config.Type, s.side.dbName, s.side.source)
+					lcheck(err, "reading primary key")
+				}
+			}
+		}
+		if len(keys) == 0 {
+			lcheck(fmt.Errorf("no primary key, specify key columns"), "comparing")
+		}
+
+		ldata, err := readCompareData(ctx, leftDB, left)
+		lcheck(err, "reading left rows")
+		rdata, err := readCompareData(ctx, rightDB, right)
+		lcheck(err, "reading right rows")
+		columns, nkeys, results, notes, err := compareRows(keys, ldata, rdata)
+		lcheck(err, "comparing")
+		script := ""
+		switch {
+		case right.isQuery():
+			notes = append(notes, "no sync script, right side is a query")
+		case hasStatus(results, "duplicate key"):
+			// updates and deletes by key would change multiple rows
+			notes = append(notes, "no sync script, key is not unique")
+		case ldata.more || rdata.more:
+			// rows beyond the limit would show up as missing on the other side, and be deleted or inserted
+			notes = append(notes, "no sync script, not all rows were read")
+		default:
+			script = syncScript(right.config.Type, right.source, columns, nkeys, ldata, columnIndices(ldata.columns, columns), results)
+		}
+
+		dui.Call <- func() {
+			if ui.gen != gen {
+				return
+			}
+			ui.cancel = nil
+			ui.columns = columns
+			ui.results = results
+			counts := map[string]int{}
+			for _, r := range results {
+				counts[r.status]++
+			}
+			msg := fmt.Sprintf("%d left only, %d right only, %d changed, of %d left and %d right rows", counts["left only"], counts["right only"], counts["changed"], len(ldata.rows), len(rdata.rows))
+			if n := counts["duplicate key"]; n > 0 {
+				msg += fmt.Sprintf(", %d with duplicate key", n)
+			}
+			if ldata.more || rdata.more {
+				msg += fmt.Sprintf(", stopped reading at %d rows", compareRowLimit)
+			}
+			if len(notes) > 0 {
+				msg += "; " + strings.Join(notes, "; ")
+			}
+			ui.message.Text = msg
+			edit, _ := duit.NewEdit(bytes.NewReader([]byte(script)))
+			ui.scriptBox.Kids = duit.NewKids(edit)
+			ui.showResults()
+		}
+	}()
+}
+
+// showResults shows the differences matching the filter.
+// Changed cells show the left and right value.
+// Called from main loop.
+func (ui *compareDataUI) showResults() {
+	defer ui.layout()
+	ui.grid.Header = &duit.Gridrow{Values: append([]string{""}, ui.columns...)}
+	ui.grid.Halign = nil
+	filter := compareFilters[ui.filter.Selected]
+	text := func(v interface{}) string {
+		if v == nil {
+			return "NULL"
+		}
+		if b, ok := v.([]byte); ok {
+			return fmt.Sprintf("0x%x", b)
+		}
+		return oneLine(fmt.Sprintf("%v", v))
+	}
+	var rows []*duit.Gridrow
+	for _, r := range ui.results {
+		if filter != "all" && filter != r.status {
+			continue
+		}
+		values := []string{r.status}
+		for i := range ui.columns {
+			switch {
+			case r.left == nil:
+				values = append(values, text(r.right[i]))
+			case r.changed[i]:
+				values = append(values, fmt.Sprintf("%s -> %s", text(r.left[i]), text(r.right[i])))
+			default:
+				values = append(values, text(r.left[i]))
+			}
+		}
+		rows = append(rows, &duit.Gridrow{Values: values})
+	}
+	ui.grid.Rows = rows
+}
+
+// readCompareData reads the rows of a side.
+// Called from outside main loop.
+func readCompareData(ctx context.Context, db *sql.DB, s compareSide) (*compareData, error) {
+	q := s.source
+	if !s.isQuery() {
+		q = "select * from " + quoteIdent(s.config.Type, s.source)
+	}
+	rows, err := db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	d := &compareData{}
+	for _, ct := range colTypes {
+		d.columns = append(d.columns, ct.Name())
+		d.kinds = append(d.kinds, columnKind(s.config.Type, ct.DatabaseTypeName()))
+	}
+	for rows.Next() {
+		if len(d.rows) >= compareRowLimit {
+			d.more = true
+			break
+		}
+		values := make([]interface{}, len(d.columns))
+		dest := make([]interface{}, len(values))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		err = rows.Scan(dest...)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err)
+		}
+		for i, v := range values {
+			values[i] = copyValue(d.kinds[i], "", v)
+		}
+		d.rows = append(d.rows, values)
+	}
+	return d, rows.Err()
+}
+
+// columnIndices returns for each of names the index in columns, matched case-insensitively, or -1.
+func columnIndices(columns, names []string) []int {
+	l := make([]int, len(names))
+	for i, name := range names {
+		l[i] = -1
+		for j, c := range columns {
+			if strings.EqualFold(c, name) {
+				l[i] = j
+				break
+			}
+		}
+	}
+	return l
+}
+
+// compareRows matches the rows of both sides on the key columns and compares the columns present on both sides.
+// Columns are returned with the key columns first.
+func compareRows(keys []string, ldata, rdata *compareData) (columns []string, nkeys int, results []compareRow, notes []string, err error) {
+	for _, k := range keys {
+		if columnIndices(ldata.columns, []string{k})[0] < 0 || columnIndices(rdata.columns, []string{k})[0] < 0 {
+			return nil, 0, nil, nil, fmt.Errorf("key column %s not on both sides", k)
+		}
+		columns = append(columns, ldata.columns[columnIndices(ldata.columns, []string{k})[0]])
+	}
+	nkeys = len(columns)
+	var leftOnly []string
+	for _, c := range ldata.columns {
+		if columnIndices(columns, []string{c})[0] >= 0 {
+			continue
+		}
+		if columnIndices(rdata.columns, []string{c})[0] >= 0 {
+			columns = append(columns, c)
+		} else {
+			leftOnly = append(leftOnly, c)
+		}
+	}
+	var rightOnly []string
+	for _, c := range rdata.columns {
+		if columnIndices(ldata.columns, []string{c})[0] < 0 {
+			rightOnly = append(rightOnly, c)
+		}
+	}
+	if len(leftOnly) > 0 {
+		notes = append(notes, "columns only left: "+strings.Join(leftOnly, ", "))
+	}
+	if len(rightOnly) > 0 {
+		notes = append(notes, "columns only right: "+strings.Join(rightOnly, ", "))
+	}
+
+	li := columnIndices(ldata.columns, columns)
+	ri := columnIndices(rdata.columns, columns)
+	pick := func(row []interface{}, indices []int) []interface{} {
+		l := make([]interface{}, len(indices))
+		for i, j := range indices {
+			l[i] = row[j]
+		}
+		return l
+	}
+	key := func(values []interface{}) string {
+		var t []string
+		for _, v := range values[:nkeys] {
+			s := fmt.Sprintf("%v", v)
+			if guidRegexp.MatchString(s) {
+				// sqlserver returns upper case, postgres lower case
+				s = strings.ToLower(s)
+			}
+			t = append(t, s)
+		}
+		return strings.Join(t, "\x00")
+	}
+
+	// rows with a key that is not unique on their side are listed as duplicate, and not compared
+	leftCount := map[string]int{}
+	for _, row := range ldata.rows {
+		leftCount[key(pick(row, li))]++
+	}
+	rightCount := map[string]int{}
+	for _, row := range rdata.rows {
+		rightCount[key(pick(row, ri))]++
+	}
+	duplicate := func(k string) bool {
+		return leftCount[k] > 1 || rightCount[k] > 1
+	}
+
+	right := map[string][]interface{}{}
+	for _, row := range rdata.rows {
+		values := pick(row, ri)
+		right[key(values)] = values
+	}
+	seen := map[string]bool{}
+	for _, row := range ldata.rows {
+		lv := pick(row, li)
+		k := key(lv)
+		seen[k] = true
+		if duplicate(k) {
+			results = append(results, compareRow{status: "duplicate key", left: lv})
+			continue
+		}
+		rv, ok := right[k]
+		if !ok {
+			results = append(results, compareRow{status: "left only", left: lv})
+			continue
+		}
+		changed := map[int]bool{}
+		for i := nkeys; i < len(columns); i++ {
+			if !sameValue(ldata.kinds[li[i]], lv[i], rdata.kinds[ri[i]], rv[i]) {
+				changed[i] = true
+			}
+		}
+		if len(changed) > 0 {
+			results = append(results, compareRow{status: "changed", left: lv, right: rv, changed: changed})
+		}
+	}
+	for _, row := range rdata.rows {
+		rv := pick(row, ri)
+		k := key(rv)
+		if duplicate(k) {
+			results = append(results, compareRow{status: "duplicate key", right: rv})
+		} else if !seen[k] {
+			results = append(results, compareRow{status: "right only", right: rv})
+		}
+	}
+	return columns, nkeys, results, notes, nil
+}
+
+// hasStatus returns whether any of results has status.
+func hasStatus(results []compareRow, status string) bool {
+	for _, r := range results {
+		if r.status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// syncScript returns statements that make table on the right equal to the left, for the compared columns.
+// leftIndices are the indices of columns in the left data, for the kinds of values.
+func syncScript(connType, table string, columns []string, nkeys int, ldata *compareData, leftIndices []int, results []compareRow) string {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdent(connType, c)
+	}
+	literal := func(i int, v interface{}) string {
+		return sqlLiteral(connType, ldata.kinds[leftIndices[i]], v)
+	}
+	where := func(values []interface{}) string {
+		var conds []string
+		for i := 0; i < nkeys; i++ {
+			conds = append(conds, fmt.Sprintf("%s = %s", quoted[i], literal(i, values[i])))
+		}
+		return strings.Join(conds, " and ")
+	}
+	t := quoteIdent(connType, table)
+	b := &strings.Builder{}
+	for _, r := range results {
+		switch r.status {
+		case "left only":
+			literals := make([]string, len(columns))
+			for i, v := range r.left {
+				literals[i] = literal(i, v)
+			}
+			fmt.Fprintf(b, "insert into %s (%s) values (%s);\n", t, strings.Join(quoted, ", "), strings.Join(literals, ", "))
+		case "changed":
+			var sets []string
+			for i := nkeys; i < len(columns); i++ {
+				if r.changed[i] {
+					sets = append(sets, fmt.Sprintf("%s = %s", quoted[i], literal(i, r.left[i])))
+				}
+			}
+			fmt.Fprintf(b, "update %s set %s where %s;\n", t, strings.Join(sets, ", "), where(r.left))
+		case "right only":
+			fmt.Fprintf(b, "delete from %s where %s;\n", t, where(r.right))
+		}
+	}
+	return b.String()
+}
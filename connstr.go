@@ -15,6 +15,7 @@ type connectionConfig struct {
 	Password string
 	Database string
 	TLS      bool
+	Format   formatConfig // How values are shown in result grids.
 }
 
 // connectionString returns an URL or connection string that can be passed to sql.Open.
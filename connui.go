@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"image"
 	"log"
 	"time"
 
@@ -18,11 +19,18 @@ type connUI struct {
 
 	cancelConnectFunc context.CancelFunc
 
-	unconnected duit.UI
-	connect     *duit.Button
-	status      *duit.Label
-	split       *duit.Split
-	databases   *filterlist.Filterlist
+	unconnected   duit.UI
+	connect       *duit.Button
+	status        *duit.Label
+	split         *duit.Split
+	databases     *filterlist.Filterlist
+	listMessage   *duit.Label // status of refreshing the databases
+	sessionsUI    *sessionsUI
+	locksUI       *locksUI
+	serverUI      *serverUI
+	rolesUI       *rolesUI
+	searchUI      *searchUI
+	compareDataUI *compareDataUI
 
 	duit.Box
 }
@@ -85,35 +93,14 @@ func newConnUI(config connectionConfig) (ui *connUI) {
 				})
 				defer handle()
 
-				var q string
-				switch ui.config.Type {
-				default:
-					panic("bad connection type")
-				case "", "postgres":
-					q = `select datname from pg_database where not datistemplate order by datname asc`
-				case "mysql":
-					q = `select schema_name from information_schema.schemata order by schema_name in ('information_schema', 'performance_schema', 'sys', 'mysql') asc, schema_name asc`
-				case "sqlserver":
-					q = `select name from master.dbo.sysdatabases where name not in ('master', 'tempdb', 'model', 'msdb') order by name asc`
-				}
-
-				var dbNames []string
-				rows, err := db.QueryContext(ctx, q)
+				dbNames, dbSizes, err := ui.listDatabases(ctx, db)
 				lcheck(err, "listing databases")
-				defer rows.Close()
-				for rows.Next() {
-					var name string
-					err = rows.Scan(&name)
-					lcheck(err, "scanning row")
-					dbNames = append(dbNames, name)
-				}
-				lcheck(rows.Err(), "reading row")
 
 				dbValues := make([]*duit.ListValue, len(dbNames))
 				var sel *duit.ListValue
 				for i, name := range dbNames {
 					lv := &duit.ListValue{
-						Text:     name,
+						Text:     databaseText(name, dbSizes[i]),
 						Value:    newDBUI(ui, name),
 						Selected: name == ui.config.Database,
 					}
@@ -159,6 +146,7 @@ func newConnUI(config connectionConfig) (ui *connUI) {
 		},
 	}
 	ui.status = &duit.Label{}
+	ui.listMessage = &duit.Label{}
 	ui.unconnected = middle(ui.status, ui.connect, edit)
 	connecting = middle(label("connecting..."), cancel)
 	ui.databases = filterlist.NewFilterlist(dui, &duit.List{Values: nil})
@@ -183,6 +171,66 @@ func newConnUI(config connectionConfig) (ui *connUI) {
 	ui.databaseBox = &duit.Box{
 		Kids: duit.NewKids(noDBUI),
 	}
+	ui.sessionsUI = newSessionsUI(ui)
+	ui.locksUI = newLocksUI(ui)
+	ui.serverUI = newServerUI(ui)
+	ui.rolesUI = newRolesUI(ui)
+	ui.searchUI = newSearchUI(ui)
+	ui.compareDataUI = newCompareDataUI(ui)
+	tools := &duit.Box{
+		Padding: duit.SpaceXY(4, 2),
+		Margin:  image.Pt(4, 2),
+		Kids: duit.NewKids(
+			&duit.Button{
+				Text: "sessions",
+				Click: func() (e duit.Event) {
+					ui.showTool(ui.sessionsUI)
+					ui.sessionsUI.load()
+					return
+				},
+			},
+			&duit.Button{
+				Text: "locks",
+				Click: func() (e duit.Event) {
+					ui.showTool(ui.locksUI)
+					ui.locksUI.load()
+					return
+				},
+			},
+			&duit.Button{
+				Text: "server",
+				Click: func() (e duit.Event) {
+					ui.showTool(ui.serverUI)
+					ui.serverUI.load()
+					return
+				},
+			},
+			&duit.Button{
+				Text: "roles",
+				Click: func() (e duit.Event) {
+					ui.showTool(ui.rolesUI)
+					ui.rolesUI.load()
+					return
+				},
+			},
+			&duit.Button{
+				Text: "search",
+				Click: func() (e duit.Event) {
+					ui.showTool(ui.searchUI)
+					dui.Focus(ui.searchUI.pattern)
+					return
+				},
+			},
+			&duit.Button{
+				Text: "compare data",
+				Click: func() (e duit.Event) {
+					ui.showTool(ui.compareDataUI)
+					dui.Focus(ui.compareDataUI.leftDB)
+					return
+				},
+			},
+		),
+	}
 	ui.split = &duit.Split{
 		Gutter:     1,
 		Background: dui.Gutter,
@@ -192,7 +240,23 @@ func newConnUI(config connectionConfig) (ui *connUI) {
 		Kids: duit.NewKids(
 			&duit.Box{
 				Kids: duit.NewKids(
-					duit.CenterUI(duit.SpaceXY(4, 2), &duit.Label{Text: "databases", Font: bold}),
+					tools,
+					&duit.Box{
+						Padding: duit.SpaceXY(4, 2),
+						Margin:  image.Pt(4, 0),
+						Valign:  duit.ValignMiddle,
+						Kids: duit.NewKids(
+							&duit.Label{Text: "databases", Font: bold},
+							&duit.Button{
+								Text: "refresh",
+								Click: func() (e duit.Event) {
+									ui.refreshDatabases()
+									return
+								},
+							},
+							ui.listMessage,
+						),
+					},
 					ui.databases,
 				),
 			},
@@ -203,6 +267,120 @@ func newConnUI(config connectionConfig) (ui *connUI) {
 	return
 }
 
+// listDatabases returns the databases on the server, with their size if known.
+// Called from outside main loop.
+func (ui *connUI) listDatabases(ctx context.Context, db *sql.DB) (names []string, sizes []sql.NullInt64, err error) {
+	var q string
+	switch ui.config.Type {
+	default:
+		panic("bad connection type")
+	case "", "postgres":
+		q = `
+			select
+				datname,
+				case when has_database_privilege(datname, 'CONNECT') then pg_database_size(datname) end
+			from pg_database
+			where not datistemplate
+			order by datname asc
+		`
+	case "mysql":
+		q = `
+			select
+				schema_name,
+				(select sum(data_length + index_length) from information_schema.tables t where t.table_schema = s.schema_name)
+			from information_schema.schemata s
+			order by schema_name in ('information_schema', 'performance_schema', 'sys', 'mysql') asc, schema_name asc
+		`
+	case "sqlserver":
+		q = `
+			select
+				d.name,
+				(select sum(cast(f.size as bigint)) * 8192 from sys.master_files f where f.database_id = d.database_id)
+			from sys.databases d
+			where d.name not in ('master', 'tempdb', 'model', 'msdb')
+			order by d.name asc
+		`
+	}
+
+	rows, err := db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		var size sql.NullInt64
+		err = rows.Scan(&name, &size)
+		if err != nil {
+			return nil, nil, fmt.Errorf("scanning row: %s", err)
+		}
+		names = append(names, name)
+		sizes = append(sizes, size)
+	}
+	err = rows.Err()
+	return
+}
+
+func databaseText(name string, size sql.NullInt64) string {
+	if size.Valid {
+		return fmt.Sprintf("%s (%s)", name, formatBytes(size.Int64))
+	}
+	return name
+}
+
+// refreshDatabases lists the databases again, keeping the connections to databases still present and the selection.
+// Called from main loop.
+func (ui *connUI) refreshDatabases() {
+	ui.listMessage.Text = "refreshing..."
+	ui.layout()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		names, sizes, err := ui.listDatabases(ctx, ui.db)
+		dui.Call <- func() {
+			defer ui.layout()
+			if err != nil {
+				ui.listMessage.Text = fmt.Sprintf("error: %s", err)
+				return
+			}
+			ui.listMessage.Text = ""
+
+			old := map[string]*duit.ListValue{}
+			for _, lv := range ui.databases.Values {
+				old[lv.Value.(*dbUI).dbName] = lv
+			}
+			var selected *dbUI
+			values := make([]*duit.ListValue, len(names))
+			for i, name := range names {
+				lv := &duit.ListValue{
+					Text:  databaseText(name, sizes[i]),
+					Value: newDBUI(ui, name),
+				}
+				if o, ok := old[name]; ok {
+					lv.Value = o.Value
+					lv.Selected = o.Selected
+					if o.Selected {
+						selected = o.Value.(*dbUI)
+					}
+				}
+				values[i] = lv
+			}
+			for _, lv := range ui.databases.Values {
+				dbUI := lv.Value.(*dbUI)
+				if dbUI == selected || containsString(names, dbUI.dbName) {
+					continue
+				}
+				if lv.Selected {
+					ui.databaseBox.Kids = duit.NewKids(middle(label("selected database no longer exists")))
+				}
+				dbUI.close()
+			}
+			ui.databases.Values = values
+			ui.databases.Filter()
+		}
+	}()
+}
+
 func (ui *connUI) splitDimensions(width int) []int {
 	if topUI.hideLeftBars {
 		return []int{0, width}
@@ -223,8 +401,41 @@ func (ui *connUI) ensureLeftBars() {
 	ui.split.Dimensions(dui, ui.splitDimensions(width))
 }
 
+// showTool shows a connection-level UI, like the sessions view, instead of a database.
+// Called from main loop.
+func (ui *connUI) showTool(tUI duit.UI) {
+	ui.databases.List.Unselect(nil)
+	ui.databaseBox.Kids = duit.NewKids(tUI)
+	dui.MarkLayout(ui)
+}
+
+// openObject selects the database and shows the object with kind and name in it, connecting to the database if needed.
+// Called from main loop.
+func (ui *connUI) openObject(dbName, kind, name string) {
+	var dUI *dbUI
+	for _, lv := range ui.databases.Values {
+		lv.Selected = lv.Value.(*dbUI).dbName == dbName
+		if lv.Selected {
+			dUI = lv.Value.(*dbUI)
+		}
+	}
+	if dUI == nil {
+		return
+	}
+	ui.databases.Search.Text = ""
+	ui.databases.Filter()
+	ui.databaseBox.Kids = duit.NewKids(dUI)
+	ui.layout()
+	if dUI.db == nil {
+		go dUI.init()
+	}
+	dUI.selectObject(kind, name)
+}
+
 func (ui *connUI) disconnect() {
-	// xxx todo: close all lower dbUI db connections
+	for _, lv := range ui.databases.Values {
+		lv.Value.(*dbUI).close()
+	}
 	ui.db.Close()
 	ui.db = nil
 	ui.Box.Kids = duit.NewKids(ui.unconnected)
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// copyFormats are the formats rows can be copied to the clipboard as, in the order shown.
+var copyFormats = []string{"tsv", "csv", "json", "markdown", "in-list", "insert"}
+
+// copyText returns the selected rows of the resultUI in format, for the visible columns.
+// If no rows are selected, all rows matching the filter are used.
+// The in-list format uses the values of the first visible column.
+// An error is returned if no columns are visible.
+// Called from main loop.
+func (ui *resultUI) copyText(format string) (string, int, error) {
+	cols := ui.visibleColumns()
+	if len(cols) == 0 {
+		return "", 0, fmt.Errorf("no visible columns")
+	}
+	var rows []int
+	for _, row := range ui.grid.Rows {
+		if row.Selected {
+			rows = append(rows, row.Value.(int))
+		}
+	}
+	if len(rows) == 0 {
+		for _, row := range ui.grid.Rows {
+			rows = append(rows, row.Value.(int))
+		}
+	}
+	names := make([]string, len(cols))
+	for i, col := range cols {
+		names[i] = ui.columns[col].name
+	}
+	connType := ui.dbUI.connUI.config.Type
+
+	var b bytes.Buffer
+	switch format {
+	case "tsv":
+		clean := strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+		line := func(l []string) {
+			for i, s := range l {
+				l[i] = clean.Replace(s)
+			}
+			b.WriteString(strings.Join(l, "\t") + "\n")
+		}
+		line(names)
+		for _, row := range rows {
+			line(ui.projectTexts(row, cols))
+		}
+	case "csv":
+		w := csv.NewWriter(&b)
+		w.Write(names)
+		for _, row := range rows {
+			w.Write(ui.projectTexts(row, cols))
+		}
+		w.Flush()
+	case "json":
+		b.WriteString("[\n")
+		for i, row := range rows {
+			b.WriteString("\t{")
+			for j, col := range cols {
+				if j > 0 {
+					b.WriteString(", ")
+				}
+				k, _ := json.Marshal(ui.columns[col].name)
+				b.Write(k)
+				b.WriteString(": ")
+				b.Write(jsonValue(ui.columns[col].kind, ui.values[row][col]))
+			}
+			b.WriteString("}")
+			if i < len(rows)-1 {
+				b.WriteString(",")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("]\n")
+	case "markdown":
+		clean := strings.NewReplacer("|", `\|`, "\n", " ", "\r", " ")
+		line := func(l []string) {
+			for i, s := range l {
+				l[i] = clean.Replace(s)
+			}
+			b.WriteString("| " + strings.Join(l, " | ") + " |\n")
+		}
+		line(names)
+		sep := make([]string, len(cols))
+		for i := range sep {
+			sep[i] = "---"
+		}
+		line(sep)
+		for _, row := range rows {
+			line(ui.projectTexts(row, cols))
+		}
+	case "in-list":
+		col := cols[0]
+		seen := map[string]bool{}
+		l := []string{}
+		for _, row := range rows {
+			s := sqlLiteral(connType, ui.columns[col].kind, ui.values[row][col])
+			if !seen[s] {
+				seen[s] = true
+				l = append(l, s)
+			}
+		}
+		b.WriteString("(" + strings.Join(l, ", ") + ")")
+	case "insert":
+		table := ui.table
+		if table == "" {
+			table = "tablename"
+		}
+		qnames := make([]string, len(cols))
+		for i, name := range names {
+			qnames[i] = quoteIdent(connType, name)
+		}
+		prefix := fmt.Sprintf("insert into %s (%s) values (", quoteIdent(connType, table), strings.Join(qnames, ", "))
+		for _, row := range rows {
+			l := make([]string, len(cols))
+			for i, col := range cols {
+				l[i] = sqlLiteral(connType, ui.columns[col].kind, ui.values[row][col])
+			}
+			b.WriteString(prefix + strings.Join(l, ", ") + ");\n")
+		}
+	default:
+		panic("bad copy format")
+	}
+	return b.String(), len(rows), nil
+}
+
+// projectTexts returns the formatted values of row for columns cols.
+func (ui *resultUI) projectTexts(row int, cols []int) []string {
+	l := make([]string, len(cols))
+	for i, col := range cols {
+		l[i] = ui.texts[row][col]
+	}
+	return l
+}
+
+// jsonValue returns raw value v of a column of kind as JSON.
+func jsonValue(kind valueKind, v interface{}) []byte {
+	switch vv := v.(type) {
+	case []byte:
+		switch kind {
+		case kindBinary:
+			v = fmt.Sprintf("%x", vv)
+		case kindGUID:
+			v = formatGUID(vv)
+		case kindNumeric:
+			if json.Valid(vv) {
+				return vv
+			}
+			v = string(vv)
+		default:
+			v = string(vv)
+		}
+	case time.Time:
+		v = vv.Format(time.RFC3339Nano)
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		buf, _ = json.Marshal(fmt.Sprintf("%v", v))
+	}
+	return buf
+}
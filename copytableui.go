@@ -0,0 +1,361 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mjl-/duit"
+)
+
+// copyTarget is where copyTableUI copies a table to.
+type copyTarget struct {
+	config connectionConfig
+	dbName string
+	table  string
+	create bool // whether to create the table
+}
+
+// copyTableUI copies a table with (some of) its rows to a table in another database, possibly of another type.
+type copyTableUI struct {
+	dbUI  *dbUI
+	table string
+
+	connection *duit.Field
+	database   *duit.Field
+	target     *duit.Field
+	create     *duit.Checkbox
+	where      *duit.Field
+	limit      *duit.Field
+	message    *duit.Label
+	ddlBox     *duit.Box
+
+	cancel context.CancelFunc // cancels the running copy, nil if none
+
+	duit.Box
+}
+
+func newCopyTableUI(dbUI *dbUI, table string) (ui *copyTableUI) {
+	ui = &copyTableUI{dbUI: dbUI, table: table}
+	ui.connection = &duit.Field{Text: dbUI.connUI.config.Name}
+	ui.database = &duit.Field{Text: dbUI.dbName}
+	ui.target = &duit.Field{Text: table}
+	ui.create = &duit.Checkbox{Checked: true}
+	ui.where = &duit.Field{Placeholder: "all rows"}
+	ui.limit = &duit.Field{Placeholder: "no limit"}
+	ui.message = &duit.Label{}
+	ui.ddlBox = &duit.Box{}
+
+	// the default target table name is unqualified when copying to another type of database
+	ui.connection.Changed = func(text string) (e duit.Event) {
+		if c, err := findConnection(text); err == nil && c.Type != dbUI.connUI.config.Type && ui.target.Text == table {
+			t := strings.Split(table, ".")
+			ui.target.Text = t[len(t)-1]
+			ui.layout()
+		}
+		return
+	}
+
+	preview := &duit.Button{
+		Text: "show create table",
+		Click: func() (e duit.Event) {
+			ui.showDDL()
+			return
+		},
+	}
+	copyButton := &duit.Button{
+		Text:     "copy",
+		Colorset: &dui.Primary,
+		Click: func() (e duit.Event) {
+			ui.startCopy()
+			return
+		},
+	}
+	cancel := &duit.Button{
+		Text: "cancel",
+		Click: func() (e duit.Event) {
+			if ui.cancel != nil {
+				ui.cancel()
+			}
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(
+		toolbar(
+			label("copy to connection"),
+			&duit.Box{Width: 150, Kids: duit.NewKids(ui.connection)},
+			label("database"),
+			&duit.Box{Width: 150, Kids: duit.NewKids(ui.database)},
+			label("table"),
+			&duit.Box{Width: 200, Kids: duit.NewKids(ui.target)},
+			ui.create,
+			label("create table"),
+		),
+		toolbar(
+			label("where"),
+			&duit.Box{Width: 300, Kids: duit.NewKids(ui.where)},
+			label("limit"),
+			&duit.Box{Width: 80, Kids: duit.NewKids(ui.limit)},
+			preview,
+			copyButton,
+			cancel,
+			ui.message,
+		),
+		ui.ddlBox,
+	)
+	return
+}
+
+func (ui *copyTableUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+// findConnection returns the config of the connection with name.
+// Called from main loop.
+func findConnection(name string) (connectionConfig, error) {
+	var names []string
+	for _, lv := range topUI.connections.Values {
+		if lv.Value == nil {
+			continue
+		}
+		c := lv.Value.(*connUI).config
+		if c.Name == name {
+			return c, nil
+		}
+		names = append(names, c.Name)
+	}
+	return connectionConfig{}, fmt.Errorf("no connection %q, connections: %s", name, strings.Join(names, ", "))
+}
+
+// copyTarget returns the target as currently set.
+// Called from main loop.
+func (ui *copyTableUI) copyTarget() (t copyTarget, err error) {
+	t.config, err = findConnection(ui.connection.Text)
+	if err != nil {
+		return
+	}
+	t.dbName = ui.database.Text
+	t.table = ui.target.Text
+	t.create = ui.create.Checked
+	if t.dbName == "" || t.table == "" {
+		return t, fmt.Errorf("database and table are required")
+	}
+	if t.config.Name == ui.dbUI.connUI.config.Name && t.dbName == ui.dbUI.dbName && t.table == ui.table {
+		return t, fmt.Errorf("target is the table itself")
+	}
+	return
+}
+
+// showDDL shows the create table statement for the target.
+// Called from main loop.
+func (ui *copyTableUI) showDDL() {
+	t, err := ui.copyTarget()
+	if err != nil {
+		ui.message.Text = fmt.Sprintf("error: %s", err)
+		ui.layout()
+		return
+	}
+	srcType := ui.dbUI.connUI.config.Type
+	go func() {
+		lcheck, handle := errorHandler(func(err error) {
+			dui.Call <- func() {
+				ui.message.Text = fmt.Sprintf("error: %s", err)
+				ui.layout()
+			}
+		})
+		defer handle()
+
+		columns, primaryKey, err := loadTableColumns(context.Background(), ui.dbUI.db, srcType, ui.dbUI.dbName, ui.table)
+		lcheck(err, "listing columns")
+		ddl := createTableSQL(srcType, t.config.Type, t.table, columns, primaryKey)
+		dui.Call <- func() {
+			edit, _ := duit.NewEdit(bytes.NewReader([]byte(ddl + ";\n")))
+			ui.ddlBox.Kids = duit.NewKids(edit)
+			ui.message.Text = ""
+			ui.layout()
+		}
+	}()
+}
+
+// startCopy starts copying the rows to the target, creating the target table if requested.
+// Called from main loop.
+func (ui *copyTableUI) startCopy() {
+	defer ui.layout()
+	if ui.cancel != nil {
+		ui.message.Text = "copy already in progress"
+		return
+	}
+	t, err := ui.copyTarget()
+	if err != nil {
+		ui.message.Text = fmt.Sprintf("error: %s", err)
+		return
+	}
+	limit := -1
+	if ui.limit.Text != "" {
+		limit, err = strconv.Atoi(ui.limit.Text)
+		if err != nil || limit < 0 {
+			ui.message.Text = "error: bad limit"
+			return
+		}
+	}
+	where := ui.where.Text
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancel = cancel
+	ui.message.Text = "copying..."
+	go ui._copy(ctx, cancel, t, where, limit)
+}
+
+// Called from outside main loop.
+func (ui *copyTableUI) _copy(ctx context.Context, cancel context.CancelFunc, t copyTarget, where string, limit int) {
+	defer cancel()
+	var dropped string // outcome of removing a created table after failure, for the message
+	lcheck, handle := errorHandler(func(err error) {
+		dui.Call <- func() {
+			ui.cancel = nil
+			if ctx.Err() == context.Canceled {
+				ui.message.Text = "canceled, nothing copied"
+			} else {
+				ui.message.Text = fmt.Sprintf("error: %s", err)
+			}
+			if dropped != "" {
+				ui.message.Text += "; " + dropped
+			}
+			ui.layout()
+		}
+	})
+	defer handle()
+
+	srcType := ui.dbUI.connUI.config.Type
+	columns, primaryKey, err := loadTableColumns(ctx, ui.dbUI.db, srcType, ui.dbUI.dbName, ui.table)
+	lcheck(err, "listing columns")
+
+	db, err := sql.Open(t.config.Type, t.config.connectionString(t.dbName))
+	lcheck(err, "connecting to target")
+	defer db.Close()
+
+	// create outside the transaction, mysql commits implicitly on DDL
+	committed := false
+	if t.create {
+		_, err = db.ExecContext(ctx, createTableSQL(srcType, t.config.Type, t.table, columns, primaryKey))
+		lcheck(err, "creating table")
+		defer func() {
+			if committed {
+				return
+			}
+			_, err := db.ExecContext(context.Background(), "drop table "+quoteIdent(t.config.Type, t.table))
+			if err != nil {
+				dropped = fmt.Sprintf("created table %s left in place, dropping: %s", t.table, err)
+			} else {
+				dropped = fmt.Sprintf("created table %s dropped", t.table)
+			}
+		}()
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	lcheck(err, "starting transaction")
+	defer tx.Rollback()
+
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = quoteIdent(srcType, c.name)
+	}
+	q := fmt.Sprintf("select %s from %s", strings.Join(names, ", "), quoteIdent(srcType, ui.table))
+	if where != "" {
+		q += " where " + where
+	}
+	if limit >= 0 {
+		if srcType == "sqlserver" {
+			q = fmt.Sprintf("select top %d %s", limit, strings.TrimPrefix(q, "select "))
+		} else {
+			q += fmt.Sprintf(" limit %d", limit)
+		}
+	}
+	rows, err := ui.dbUI.db.QueryContext(ctx, q)
+	lcheck(err, "reading rows")
+	defer rows.Close()
+	colTypes, err := rows.ColumnTypes()
+	lcheck(err, "reading column types")
+	kinds := make([]valueKind, len(colTypes))
+	for i, ct := range colTypes {
+		kinds[i] = columnKind(srcType, ct.DatabaseTypeName())
+	}
+
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	batch := maxParams(t.config.Type) / len(columns)
+	if batch > importBatch {
+		batch = importBatch
+	}
+	var args []interface{}
+	var copied int
+	flush := func() {
+		n := len(args) / len(columns)
+		if n == 0 {
+			return
+		}
+		_, err := tx.ExecContext(ctx, insertSQL(t.config.Type, t.table, names, n), args...)
+		lcheck(err, "inserting rows")
+		args = args[:0]
+		copied += n
+		msg := fmt.Sprintf("copied %d rows...", copied)
+		dui.Call <- func() {
+			ui.message.Text = msg
+			ui.layout()
+		}
+	}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		err = rows.Scan(dest...)
+		lcheck(err, "scanning row")
+		for i, v := range values {
+			args = append(args, copyValue(kinds[i], typeClass(srcType, columns[i]), v))
+		}
+		if len(args)/len(columns) >= batch {
+			flush()
+		}
+	}
+	lcheck(rows.Err(), "reading rows")
+	flush()
+	err = tx.Commit()
+	lcheck(err, "committing")
+	committed = true
+
+	dui.Call <- func() {
+		ui.cancel = nil
+		ui.message.Text = fmt.Sprintf("copied %d rows to %s in %s on %s", copied, t.table, t.dbName, t.config.Name)
+		ui.layout()
+	}
+}
+
+// copyValue converts a value read from the source database for use as parameter in the target database.
+func copyValue(kind valueKind, class string, v interface{}) interface{} {
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+	switch {
+	case kind == kindGUID:
+		return formatGUID(b)
+	case class == "bool" && len(b) == 1:
+		// mysql bit(1)
+		return b[0] != 0
+	case class == "bigint" && kind == kindBinary:
+		// mysql bit(n), big-endian
+		var x int64
+		for _, c := range b {
+			x = x<<8 | int64(c)
+		}
+		return x
+	case kind == kindBinary:
+		return b
+	}
+	return string(b)
+}
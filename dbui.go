@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"image"
 	"time"
 
+	"9fans.net/go/draw"
 	"github.com/mjl-/duit"
 	"github.com/mjl-/filterlist"
 )
@@ -15,8 +17,13 @@ type dbUI struct {
 	dbName string
 	db     *sql.DB
 
-	tables    *filterlist.Filtergridlist
-	contentUI *duit.Box // holds 1 kid, the editUI, tableUI, viewUI or placeholder label
+	tables      *filterlist.Filtergridlist
+	tree        []*objectNode // <sql>, <sizes>, <find data>, <dump/restore>, <diagram>, <notifications> for postgres, and objects grouped by schema and kind
+	listMessage *duit.Label   // status of refreshing the objects
+	pending     *dbObject     // object to select after listing objects
+	selected    *duit.Gridrow // row of selected object, kept selected when expanding/collapsing groups
+	editUI      *editUI
+	contentUI   *duit.Box // holds 1 kid, the editUI, tableUI, viewUI or placeholder label
 
 	duit.Box // holds either box with status message, or box with tables and contentUI
 }
@@ -70,115 +77,107 @@ func (ui *dbUI) init() {
 		ui.layout()
 	}
 
-	var q string
-	var args []interface{}
-	switch ui.connUI.config.Type {
-	case "postgres":
-		q = `
-			select
-				table_type = 'VIEW' as is_view,
-				table_schema || '.' || table_name as name
-			from information_schema.tables
-			order by table_schema in ('pg_catalog', 'information_schema') asc, name asc
-		`
-	case "mysql":
-		// in mysql, schema & database are the same concept, so no need to add the schema to the name here
-		q = `
-			select
-				table_type like '%VIEW' as is_view,
-				table_name as name
-			from information_schema.tables
-			where table_schema = ?
-			order by name asc
-		`
-		args = append(args, ui.dbName)
-	case "sqlserver":
-		q = `
-			select
-				case table_type when 'VIEW' then 1 else 0 end,
-				concat(table_schema, '.', table_name) as name
-			from information_schema.tables
-			order by name
-		`
-	default:
-		panic("bad connection type")
-	}
-	type object struct {
-		IsView bool   `json:"is_view"`
-		Name   string `json:"name"`
-	}
-	var objects []object
-	rows, err := db.QueryContext(ctx, q, args...)
-	lcheck(err, "listing tables and views")
-	defer rows.Close()
-	for rows.Next() {
-		var o object
-		err = rows.Scan(&o.IsView, &o.Name)
-		lcheck(err, "scanning row")
-		objects = append(objects, o)
-	}
-	lcheck(rows.Err(), "reading row")
+	objects, err := ui.listObjects(ctx, db)
+	lcheck(err, "listing objects")
 
 	eUI := newEditUI(ui)
-	values := make([]*duit.Gridrow, 1+len(objects))
-	values[0] = &duit.Gridrow{
-		Selected: true,
-		Values:   []string{"", "<sql>"},
-		Value:    eUI,
+	sqlNode := &objectNode{
+		label: "<sql>",
+		row: &duit.Gridrow{
+			Selected: true,
+			Values:   []string{"", ""},
+			Value:    eUI,
+		},
+	}
+	sizesNode := &objectNode{
+		label: "<sizes>",
+		row: &duit.Gridrow{
+			Values: []string{"", ""},
+			Value:  newResultUI(ui, ui.sizesQuery()),
+		},
 	}
+	uis := make([]duit.UI, len(objects))
 	for i, obj := range objects {
-		var objUI duit.UI
-		var kind string
-		if obj.IsView {
-			objUI = newViewUI(ui, obj.Name)
-			kind = "V "
-		} else {
-			objUI = newTableUI(ui, obj.Name)
-			kind = "T "
-		}
-		values[i+1] = &duit.Gridrow{
-			Values: []string{
-				kind,
-				obj.Name,
+		uis[i] = ui.newObjectUI(obj)
+	}
+	findNode := &objectNode{
+		label: "<find data>",
+		row: &duit.Gridrow{
+			Values: []string{"", ""},
+			Value:  newFindDataUI(ui),
+		},
+	}
+	dumpNode := &objectNode{
+		label: "<dump/restore>",
+		row: &duit.Gridrow{
+			Values: []string{"", ""},
+			Value:  newDumpUI(ui),
+		},
+	}
+	diagramNode := &objectNode{
+		label: "<diagram>",
+		row: &duit.Gridrow{
+			Values: []string{"", ""},
+			Value:  newDiagramUI(ui),
+		},
+	}
+	tree := []*objectNode{sqlNode, sizesNode, findNode, dumpNode, diagramNode}
+	if ui.connUI.config.Type == "postgres" {
+		tree = append(tree, &objectNode{
+			label: "<notifications>",
+			row: &duit.Gridrow{
+				Values: []string{"", ""},
+				Value:  newNotifyUI(ui),
 			},
-			Value: objUI,
-		}
+		})
 	}
+	tree = append(tree, buildObjectTree(ui.connUI.config.Type, objects, uis)...)
 
 	dui.Call <- func() {
 		defer ui.layout()
 		ui.db = db
+		ui.editUI = eUI
+		ui.tree = tree
+		ui.listMessage = &duit.Label{}
+		ui.selected = sqlNode.row
 		gridlist := &duit.Gridlist{
 			Fit:    duit.FitSlim,
 			Halign: []duit.Halign{duit.HalignMiddle, duit.HalignLeft},
-			Rows:   values,
+			Rows:   objectRows(tree, ""),
 			Changed: func(index int) (e duit.Event) {
 				lv := ui.tables.Gridlist.Rows[index]
-				var selUI, focusUI duit.UI
-				if !lv.Selected {
-					selUI = middle(label("select <sql>, or a a table or view on the left"))
-				} else {
-					selUI = lv.Value.(duit.UI)
-					switch objUI := selUI.(type) {
-					case *editUI:
-						focusUI = objUI.edit
-					case *tableUI:
-						objUI.init()
-						focusUI = objUI.tabsUI.Buttongroup
-					case *viewUI:
-						objUI.init()
-						focusUI = objUI.tabsUI.Buttongroup
+				if n, ok := lv.Value.(*objectNode); ok {
+					// clicking a schema or kind expands/collapses it, keeping the selected object
+					n.expanded = !n.expanded
+					lv.Selected = false
+					if ui.selected != nil {
+						ui.selected.Selected = true
 					}
+					ui.filterObjects()
+					return
 				}
-				ui.contentUI.Kids = duit.NewKids(selUI)
-				ui.layout()
-				if focusUI != nil {
-					dui.Focus(focusUI)
+				ui.selected = nil
+				if lv.Selected {
+					ui.selected = lv
 				}
+				ui.showSelected()
 				return
 			},
 		}
 		ui.tables = filterlist.NewFiltergridlist(dui, gridlist)
+		ui.tables.Search.Changed = func(string) (e duit.Event) {
+			ui.filterObjects()
+			return
+		}
+		keys := ui.tables.Search.Keys
+		ui.tables.Search.Keys = func(k rune, m draw.Mouse) (e duit.Event) {
+			e = keys(k, m)
+			if k == 'f'&0x1f {
+				// completion filters the flat list, restore the tree
+				ui.filterObjects()
+			}
+			return
+		}
 		ui.contentUI = &duit.Box{
 			Kids: duit.NewKids(eUI),
 		}
@@ -196,7 +195,22 @@ func (ui *dbUI) init() {
 				Kids: duit.NewKids(
 					&duit.Box{
 						Kids: duit.NewKids(
-							duit.CenterUI(duit.SpaceXY(4, 2), &duit.Label{Text: "tables", Font: bold}),
+							&duit.Box{
+								Padding: duit.SpaceXY(4, 2),
+								Margin:  image.Pt(4, 0),
+								Valign:  duit.ValignMiddle,
+								Kids: duit.NewKids(
+									&duit.Label{Text: "tables", Font: bold},
+									&duit.Button{
+										Text: "refresh",
+										Click: func() (e duit.Event) {
+											ui.refreshObjects()
+											return
+										},
+									},
+									ui.listMessage,
+								),
+							},
 							ui.tables,
 						),
 					},
@@ -205,5 +219,362 @@ func (ui *dbUI) init() {
 			},
 		)
 		ui.Box.Kids[0].ID = "tables"
+		if ui.pending != nil {
+			obj := ui.pending
+			ui.pending = nil
+			ui.selectObject(obj.Kind, obj.Name)
+		}
+	}
+}
+
+// listObjects returns the tables, views, routines, triggers and sequences in the database.
+// Called from outside main loop.
+func (ui *dbUI) listObjects(ctx context.Context, db *sql.DB) (objects []dbObject, err error) {
+	// each query returns the kind (T for table, V view, F function, P procedure, TR trigger, S sequence),
+	// the name as shown and a key identifying the object for the structure queries.
+	var q string
+	var args []interface{}
+	switch ui.connUI.config.Type {
+	case "postgres":
+		q = `
+			select kind, name, key from (
+				select
+					case table_type when 'VIEW' then 'V' else 'T' end as kind,
+					table_schema as schema,
+					table_schema || '.' || table_name as name,
+					table_schema || '.' || table_name as key
+				from information_schema.tables
+				union all
+				select
+					case when p.prokind = 'p' then 'P' else 'F' end,
+					n.nspname,
+					n.nspname || '.' || p.proname || '(' || pg_get_function_identity_arguments(p.oid) || ')',
+					p.oid::text
+				from pg_proc p
+				join pg_namespace n on p.pronamespace = n.oid
+				where p.prokind in ('f', 'p')
+				union all
+				select
+					'TR',
+					n.nspname,
+					n.nspname || '.' || c.relname || '.' || t.tgname,
+					t.oid::text
+				from pg_trigger t
+				join pg_class c on t.tgrelid = c.oid
+				join pg_namespace n on c.relnamespace = n.oid
+				where not t.tgisinternal
+				union all
+				select
+					'S',
+					sequence_schema,
+					sequence_schema || '.' || sequence_name,
+					sequence_schema || '.' || sequence_name
+				from information_schema.sequences
+			) x
+			order by schema in ('pg_catalog', 'information_schema') asc, name asc
+		`
+	case "mysql":
+		// in mysql, schema & database are the same concept, so no need to add the schema to the name here
+		q = `
+			select kind, name, key from (
+				select
+					case when table_type like '%VIEW' then 'V' else 'T' end as kind,
+					table_name as name,
+					table_name as ` + "`key`" + `
+				from information_schema.tables
+				where table_schema = ?
+				union all
+				select
+					case routine_type when 'PROCEDURE' then 'P' else 'F' end,
+					routine_name,
+					routine_name
+				from information_schema.routines
+				where routine_schema = ?
+				union all
+				select
+					'TR',
+					concat(event_object_table, '.', trigger_name),
+					trigger_name
+				from information_schema.triggers
+				where trigger_schema = ?
+			) x
+			order by name asc
+		`
+		args = append(args, ui.dbName, ui.dbName, ui.dbName)
+	case "sqlserver":
+		q = `
+			select kind, name, key from (
+				select
+					case table_type when 'VIEW' then 'V' else 'T' end as kind,
+					concat(table_schema, '.', table_name) as name,
+					concat(table_schema, '.', table_name) as key
+				from information_schema.tables
+				union all
+				select
+					case o.type when 'P' then 'P' else 'F' end,
+					concat(schema_name(o.schema_id), '.', o.name),
+					cast(o.object_id as varchar(20))
+				from sys.objects o
+				where o.type in ('P', 'FN', 'IF', 'TF') and o.is_ms_shipped = 0
+				union all
+				select
+					'TR',
+					concat(object_schema_name(t.parent_id), '.', object_name(t.parent_id), '.', t.name),
+					cast(t.object_id as varchar(20))
+				from sys.triggers t
+				where t.parent_class = 1
+				union all
+				select
+					'S',
+					concat(schema_name(s.schema_id), '.', s.name),
+					cast(s.object_id as varchar(20))
+				from sys.sequences s
+			) x
+			order by name
+		`
+	default:
+		panic("bad connection type")
+	}
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var o dbObject
+		err = rows.Scan(&o.Kind, &o.Name, &o.Key)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err)
+		}
+		objects = append(objects, o)
+	}
+	return objects, rows.Err()
+}
+
+// showSelected shows the UI of the selected object, or a placeholder if nothing is selected.
+// Called from main loop.
+func (ui *dbUI) showSelected() {
+	var selUI, focusUI duit.UI
+	if ui.selected == nil {
+		selUI = middle(label("select <sql>, or a table, view or other object on the left"))
+	} else {
+		selUI = ui.selected.Value.(duit.UI)
+		switch objUI := selUI.(type) {
+		case *editUI:
+			focusUI = objUI.edit
+		case *tableUI:
+			objUI.init()
+			focusUI = objUI.tabsUI.Buttongroup
+		case *viewUI:
+			objUI.init()
+			focusUI = objUI.tabsUI.Buttongroup
+		case *resultUI:
+			if objUI.Box.Kids == nil {
+				go objUI.load(objUI.compute)
+			}
+		case *objectstructUI:
+			if objUI.Box.Kids == nil {
+				objUI.init()
+			}
+		case *findDataUI:
+			focusUI = objUI.value
+		case *dumpUI:
+			objUI.listTables()
+		case *diagramUI:
+			objUI.init()
+		}
+	}
+	ui.contentUI.Kids = duit.NewKids(selUI)
+	ui.layout()
+	if focusUI != nil {
+		dui.Focus(focusUI)
+	}
+}
+
+// close stops listening for notifications and closes the connection to the database.
+// Called from main loop.
+func (ui *dbUI) close() {
+	for _, n := range ui.tree {
+		if nUI, ok := n.row.Value.(*notifyUI); ok {
+			nUI.stop()
+		}
+	}
+	if ui.db != nil {
+		ui.db.Close()
+	}
+}
+
+// showEditor selects and shows <sql>.
+// Called from main loop.
+func (ui *dbUI) showEditor() {
+	if ui.selected != nil {
+		ui.selected.Selected = false
+	}
+	ui.selected = ui.tree[0].row
+	ui.selected.Selected = true
+	ui.tables.Search.Text = ""
+	ui.filterObjects()
+	ui.showSelected()
+}
+
+// selectObject selects and shows the object with kind and name, expanding the groups it is in.
+// If the objects have not been listed yet, the object is selected once they are.
+// Called from main loop.
+func (ui *dbUI) selectObject(kind, name string) {
+	if ui.tables == nil {
+		ui.pending = &dbObject{Kind: kind, Name: name}
+		return
+	}
+	var find func(l []*objectNode) *objectNode
+	find = func(l []*objectNode) *objectNode {
+		for _, n := range l {
+			if n.children == nil {
+				if n.object.Kind == kind && n.object.Name == name {
+					return n
+				}
+			} else if o := find(n.children); o != nil {
+				n.expanded = true
+				return o
+			}
+		}
+		return nil
+	}
+	n := find(ui.tree)
+	if n == nil {
+		return
+	}
+	if ui.selected != nil {
+		ui.selected.Selected = false
+	}
+	n.row.Selected = true
+	ui.selected = n.row
+	ui.tables.Search.Text = ""
+	ui.filterObjects()
+	ui.showSelected()
+}
+
+// newObjectUI returns the UI showing obj when selected.
+func (ui *dbUI) newObjectUI(obj dbObject) duit.UI {
+	switch obj.Kind {
+	case "V":
+		return newViewUI(ui, obj.Name)
+	case "T":
+		return newTableUI(ui, obj.Name)
+	}
+	return newObjectStructUI(ui, obj.Kind, obj.Name, obj.Key)
+}
+
+// refreshObjects lists the objects again, keeping the state of objects still present, the expanded groups and the selection.
+// Called from main loop.
+func (ui *dbUI) refreshObjects() {
+	ui.listMessage.Text = "refreshing..."
+	ui.layout()
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		objects, err := ui.listObjects(ctx, ui.db)
+		dui.Call <- func() {
+			defer ui.layout()
+			if err != nil {
+				ui.listMessage.Text = fmt.Sprintf("error: %s", err)
+				return
+			}
+			ui.listMessage.Text = ""
+
+			old := map[dbObject]duit.UI{}
+			walkObjects(ui.tree, func(n *objectNode) {
+				old[n.object] = n.row.Value.(duit.UI)
+			})
+			uis := make([]duit.UI, len(objects))
+			for i, obj := range objects {
+				uis[i] = old[obj]
+				if uis[i] == nil {
+					uis[i] = ui.newObjectUI(obj)
+				}
+			}
+			// keep <sql>, <sizes>, <find data>, <dump/restore>, <diagram> and <notifications>
+			n := 0
+			for n < len(ui.tree) && ui.tree[n].children == nil && ui.tree[n].object.Kind == "" {
+				n++
+			}
+			tree := append(ui.tree[:n:n], buildObjectTree(ui.connUI.config.Type, objects, uis)...)
+			copyExpanded(ui.tree, tree)
+			ui.tree = tree
+
+			if ui.selected != nil {
+				var sel *duit.Gridrow
+				walkObjects(ui.tree, func(n *objectNode) {
+					if n.row.Value == ui.selected.Value {
+						sel = n.row
+					}
+				})
+				ui.selected = sel
+				if sel == nil {
+					ui.contentUI.Kids = duit.NewKids(middle(label("selected object no longer exists")))
+				} else {
+					sel.Selected = true
+				}
+			}
+			ui.filterObjects()
+		}
+	}()
+}
+
+// filterObjects shows the rows of the object tree matching the search field.
+// Called from main loop.
+func (ui *dbUI) filterObjects() {
+	ui.tables.Gridlist.Rows = objectRows(ui.tree, ui.tables.Search.Text)
+	dui.MarkLayout(ui.tables)
+}
+
+// sizesQuery returns a query listing the tables in the database with their row estimate and sizes in bytes, largest first.
+func (ui *dbUI) sizesQuery() string {
+	switch ui.connUI.config.Type {
+	case "postgres":
+		return `
+			select
+				n.nspname || '.' || c.relname as name,
+				c.reltuples::bigint as row_estimate,
+				round(pg_total_relation_size(c.oid) / 1048576.0, 1) as total_mib,
+				pg_total_relation_size(c.oid) as total_bytes,
+				pg_relation_size(c.oid) as data_bytes,
+				pg_indexes_size(c.oid) as index_bytes,
+				coalesce(pg_total_relation_size(nullif(c.reltoastrelid, 0)), 0) as toast_bytes
+			from pg_class c
+			join pg_namespace n on c.relnamespace = n.oid
+			where c.relkind in ('r', 'm', 'p')
+			order by total_bytes desc
+		`
+	case "mysql":
+		return `
+			select
+				table_name as name,
+				table_rows as row_estimate,
+				round((data_length + index_length) / 1048576, 1) as total_mib,
+				data_length + index_length as total_bytes,
+				data_length as data_bytes,
+				index_length as index_bytes,
+				0 as lob_bytes
+			from information_schema.tables
+			where table_schema = database() and table_type = 'BASE TABLE'
+			order by total_bytes desc
+		`
+	case "sqlserver":
+		return `
+			select
+				concat(s.name, '.', t.name) as name,
+				sum(case when ps.index_id in (0, 1) then ps.row_count else 0 end) as row_estimate,
+				round(sum(ps.reserved_page_count) * 8192 / 1048576.0, 1) as total_mib,
+				sum(ps.reserved_page_count) * 8192 as total_bytes,
+				sum(case when ps.index_id in (0, 1) then ps.in_row_data_page_count else 0 end) * 8192 as data_bytes,
+				sum(case when ps.index_id > 1 then ps.in_row_data_page_count else 0 end) * 8192 as index_bytes,
+				sum(ps.lob_used_page_count + ps.row_overflow_used_page_count) * 8192 as lob_bytes
+			from sys.dm_db_partition_stats ps
+			join sys.tables t on ps.object_id = t.object_id
+			join sys.schemas s on t.schema_id = s.schema_id
+			group by s.name, t.name
+			order by total_bytes desc
+		`
 	}
+	panic("bad connection type")
 }
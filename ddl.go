@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// tableColumn describes a column of a table, as read from information_schema.
+type tableColumn struct {
+	name      string
+	dataType  string // lower case
+	length    sql.NullInt64
+	precision sql.NullInt64
+	scale     sql.NullInt64
+	nullable  bool
+	generated bool // identity, serial or auto_increment, the database generates values
+}
+
+// loadTableColumns returns the columns of table and the columns of its primary key.
+func loadTableColumns(ctx context.Context, db *sql.DB, connType, dbName, table string) (columns []tableColumn, primaryKey []string, err error) {
+	var qColumns, qKey string
+	var args []interface{}
+	switch connType {
+	case "postgres":
+		qColumns = `
+			select
+				column_name, data_type, character_maximum_length, numeric_precision, numeric_scale, is_nullable = 'YES',
+				coalesce(is_identity, 'NO') = 'YES' or coalesce(column_default, '') like 'nextval(%'
+			from information_schema.columns
+			where table_schema || '.' || table_name=$1
+			order by ordinal_position
+		`
+		qKey = `
+			select kcu.column_name
+			from information_schema.table_constraints tc
+			join information_schema.key_column_usage kcu on tc.constraint_schema = kcu.constraint_schema and tc.constraint_name = kcu.constraint_name
+			where tc.constraint_type = 'PRIMARY KEY' and tc.table_schema || '.' || tc.table_name=$1
+			order by kcu.ordinal_position
+		`
+		args = append(args, table)
+	case "mysql":
+		qColumns = `
+			select column_name, data_type, character_maximum_length, numeric_precision, numeric_scale, is_nullable = 'YES', extra like '%auto_increment%'
+			from information_schema.columns
+			where table_schema=? and table_name=?
+			order by ordinal_position
+		`
+		qKey = `
+			select column_name
+			from information_schema.key_column_usage
+			where constraint_name = 'PRIMARY' and table_schema=? and table_name=?
+			order by ordinal_position
+		`
+		args = append(args, dbName, table)
+	case "sqlserver":
+		qColumns = `
+			select
+				column_name, data_type, character_maximum_length, numeric_precision, numeric_scale, case when is_nullable = 'YES' then 1 else 0 end,
+				coalesce(columnproperty(object_id(concat(quotename(table_schema), '.', quotename(table_name))), column_name, 'IsIdentity'), 0)
+			from information_schema.columns
+			where concat(table_schema, '.', table_name)=@name
+			order by ordinal_position
+		`
+		qKey = `
+			select kcu.column_name
+			from information_schema.table_constraints tc
+			join information_schema.key_column_usage kcu on tc.constraint_schema = kcu.constraint_schema and tc.constraint_name = kcu.constraint_name
+			where tc.constraint_type = 'PRIMARY KEY' and concat(tc.table_schema, '.', tc.table_name)=@name
+			order by kcu.ordinal_position
+		`
+		args = append(args, sql.Named("name", table))
+	default:
+		panic("bad connection type")
+	}
+
+	rows, err := db.QueryContext(ctx, qColumns, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c tableColumn
+		err = rows.Scan(&c.name, &c.dataType, &c.length, &c.precision, &c.scale, &c.nullable, &c.generated)
+		if err != nil {
+			return nil, nil, fmt.Errorf("scanning row: %s", err)
+		}
+		c.dataType = strings.ToLower(c.dataType)
+		columns = append(columns, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+	if len(columns) == 0 {
+		return nil, nil, fmt.Errorf("no columns found for table %s", table)
+	}
+
+	rows, err = db.QueryContext(ctx, qKey, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		err = rows.Scan(&name)
+		if err != nil {
+			return nil, nil, fmt.Errorf("scanning row: %s", err)
+		}
+		primaryKey = append(primaryKey, name)
+	}
+	return columns, primaryKey, rows.Err()
+}
+
+// typeClass returns a dialect-independent class for the data type of column c of connType, for translating types between dialects.
+func typeClass(connType string, c tableColumn) string {
+	switch c.dataType {
+	case "smallint", "tinyint":
+		return "smallint"
+	case "integer", "int", "mediumint":
+		return "int"
+	case "bigint":
+		return "bigint"
+	case "boolean":
+		return "bool"
+	case "bit":
+		if connType == "mysql" && c.precision.Int64 > 1 {
+			// mysql bit(n) holds n bits, only bit(1) is used as bool
+			return "bigint"
+		}
+		return "bool"
+	case "numeric", "decimal", "money", "smallmoney":
+		return "numeric"
+	case "real":
+		return "float"
+	case "float":
+		if connType == "sqlserver" {
+			return "double"
+		}
+		return "float"
+	case "double precision", "double":
+		return "double"
+	case "character", "char", "nchar", "bpchar":
+		return "char"
+	case "character varying", "varchar", "nvarchar":
+		return "varchar"
+	case "text", "ntext", "tinytext", "mediumtext", "longtext", "citext", "xml", "enum", "set":
+		return "text"
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "image":
+		return "binary"
+	case "date":
+		return "date"
+	case "time", "time without time zone", "time with time zone":
+		return "time"
+	case "timestamp":
+		if connType == "sqlserver" {
+			// rowversion
+			return "binary"
+		}
+		return "timestamp"
+	case "timestamp without time zone", "datetime", "datetime2", "smalldatetime":
+		return "timestamp"
+	case "timestamp with time zone", "datetimeoffset":
+		return "timestamptz"
+	case "uuid", "uniqueidentifier":
+		return "uuid"
+	case "json", "jsonb":
+		return "json"
+	}
+	return "other"
+}
+
+// columnTypeSQL returns the type for column c of a table in a database of type srcType, for use in a database of type dstType.
+func columnTypeSQL(srcType, dstType string, c tableColumn) string {
+	pick := func(pg, my, ms string) string {
+		switch dstType {
+		case "postgres":
+			return pg
+		case "mysql":
+			return my
+		}
+		return ms
+	}
+	n := c.length.Int64
+	switch typeClass(srcType, c) {
+	case "smallint":
+		return "smallint"
+	case "int":
+		return pick("integer", "int", "int")
+	case "bigint":
+		return "bigint"
+	case "bool":
+		return pick("boolean", "boolean", "bit")
+	case "numeric":
+		if c.precision.Valid && c.precision.Int64 > 0 {
+			p, s := c.precision.Int64, c.scale.Int64
+			max := map[string]int64{"postgres": 1000, "mysql": 65, "sqlserver": 38}[dstType]
+			if p > max {
+				p = max
+			}
+			if s > p {
+				s = p
+			}
+			return fmt.Sprintf("%s(%d,%d)", pick("numeric", "decimal", "decimal"), p, s)
+		}
+		return pick("numeric", "decimal(65,30)", "decimal(38,10)")
+	case "float":
+		return pick("real", "float", "real")
+	case "double":
+		return pick("double precision", "double", "float")
+	case "char":
+		if n > 0 {
+			return fmt.Sprintf("%s(%d)", pick("char", "char", "nchar"), n)
+		}
+		return pick("char", "char", "nchar")
+	case "varchar":
+		switch {
+		case n <= 0:
+			return pick("text", "longtext", "nvarchar(max)")
+		case dstType == "mysql" && n > 16383:
+			return "longtext"
+		case dstType == "sqlserver" && n > 4000:
+			return "nvarchar(max)"
+		}
+		return fmt.Sprintf("%s(%d)", pick("varchar", "varchar", "nvarchar"), n)
+	case "text":
+		return pick("text", "longtext", "nvarchar(max)")
+	case "binary":
+		return pick("bytea", "longblob", "varbinary(max)")
+	case "date":
+		return "date"
+	case "time":
+		return "time"
+	case "timestamp":
+		return pick("timestamp", "datetime(6)", "datetime2")
+	case "timestamptz":
+		return pick("timestamptz", "datetime(6)", "datetimeoffset")
+	case "uuid":
+		return pick("uuid", "char(36)", "uniqueidentifier")
+	case "json":
+		return pick("jsonb", "json", "nvarchar(max)")
+	}
+	if srcType == dstType && c.dataType != "array" && c.dataType != "user-defined" {
+		return c.dataType
+	}
+	return pick("text", "longtext", "nvarchar(max)")
+}
+
+// createTableSQL returns a create table statement for use in a database of type dstType,
+// for a table with columns from a database of type srcType.
+func createTableSQL(srcType, dstType, table string, columns []tableColumn, primaryKey []string) string {
+	var lines []string
+	for _, c := range columns {
+		line := fmt.Sprintf("\t%s %s", quoteIdent(dstType, c.name), columnTypeSQL(srcType, dstType, c))
+		if !c.nullable {
+			line += " not null"
+		}
+		lines = append(lines, line)
+	}
+	if len(primaryKey) > 0 {
+		quoted := make([]string, len(primaryKey))
+		for i, name := range primaryKey {
+			quoted[i] = quoteIdent(dstType, name)
+		}
+		lines = append(lines, fmt.Sprintf("\tprimary key (%s)", strings.Join(quoted, ", ")))
+	}
+	return fmt.Sprintf("create table %s (\n%s\n)", quoteIdent(dstType, table), strings.Join(lines, ",\n"))
+}
+
+// insertSQL returns an insert statement for nrows rows with parameters for all values, in the parameter style of connType.
+func insertSQL(connType, table string, columns []string, nrows int) string {
+	quoted := make([]string, len(columns))
+	for i, name := range columns {
+		quoted[i] = quoteIdent(connType, name)
+	}
+	var values []string
+	n := 0
+	for r := 0; r < nrows; r++ {
+		params := make([]string, len(columns))
+		for i := range params {
+			n++
+			switch connType {
+			case "postgres":
+				params[i] = fmt.Sprintf("$%d", n)
+			case "mysql":
+				params[i] = "?"
+			case "sqlserver":
+				params[i] = fmt.Sprintf("@p%d", n)
+			}
+		}
+		values = append(values, "("+strings.Join(params, ", ")+")")
+	}
+	return fmt.Sprintf("insert into %s (%s) values %s", quoteIdent(connType, table), strings.Join(quoted, ", "), strings.Join(values, ", "))
+}
+
+// maxParams returns the maximum number of parameters in a statement for connType.
+func maxParams(connType string) int {
+	switch connType {
+	case "postgres":
+		return 65000
+	case "mysql":
+		return 60000
+	}
+	return 2000
+}
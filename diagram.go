@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"image"
+	"sort"
+	"strings"
+
+	"9fans.net/go/draw"
+)
+
+const (
+	diagramPad       = 6    // padding inside table boxes
+	diagramGapX      = 80   // between columns of boxes
+	diagramGapY      = 30   // between boxes in a column
+	diagramMaxHeight = 1200 // height after which a column of boxes wraps
+	diagramTextScale = 0.8  // minimum scale at which column names are drawn
+)
+
+// diagramTable is a table in an ER diagram, with its box in diagram coordinates.
+type diagramTable struct {
+	name       string
+	columns    []findColumn
+	primaryKey map[string]bool
+	r          image.Rectangle
+}
+
+// diagram is an ER diagram of tables and the foreign keys between them.
+type diagram struct {
+	tables     []*diagramTable
+	byName     map[string]*diagramTable
+	fks        []foreignKey // only between tables in the diagram
+	lineHeight int
+}
+
+// listPrimaryKeys returns the columns of the primary keys of the tables in the database.
+// Called from outside main loop.
+func listPrimaryKeys(ctx context.Context, db *sql.DB, connType string) (map[string][]string, error) {
+	var q string
+	switch connType {
+	case "postgres":
+		q = `
+			select tc.table_schema || '.' || tc.table_name, kcu.column_name
+			from information_schema.table_constraints tc
+			join information_schema.key_column_usage kcu on tc.constraint_schema = kcu.constraint_schema and tc.constraint_name = kcu.constraint_name
+			where tc.constraint_type = 'PRIMARY KEY'
+			order by 1, kcu.ordinal_position
+		`
+	case "mysql":
+		q = `
+			select table_name, column_name
+			from information_schema.key_column_usage
+			where constraint_name = 'PRIMARY' and table_schema = database()
+			order by 1, ordinal_position
+		`
+	case "sqlserver":
+		q = `
+			select concat(tc.table_schema, '.', tc.table_name), kcu.column_name
+			from information_schema.table_constraints tc
+			join information_schema.key_column_usage kcu on tc.constraint_schema = kcu.constraint_schema and tc.constraint_name = kcu.constraint_name
+			where tc.constraint_type = 'PRIMARY KEY'
+			order by 1, kcu.ordinal_position
+		`
+	default:
+		panic("bad connection type")
+	}
+	rows, err := db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	keys := map[string][]string{}
+	for rows.Next() {
+		var table, column string
+		err = rows.Scan(&table, &column)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err)
+		}
+		keys[table] = append(keys[table], column)
+	}
+	return keys, rows.Err()
+}
+
+// newDiagram returns a diagram with the focus table and the tables up to levels foreign keys away from it,
+// or all tables if focus is empty.
+func newDiagram(tables []string, columns map[string][]findColumn, primaryKeys map[string][]string, fks []foreignKey, focus string, levels int) (*diagram, error) {
+	include := map[string]bool{}
+	if focus == "" {
+		for _, t := range tables {
+			include[t] = true
+		}
+	} else {
+		if _, ok := columns[focus]; !ok {
+			return nil, fmt.Errorf("no table %s", focus)
+		}
+		include[focus] = true
+		for level := 0; level < levels; level++ {
+			var add []string
+			for _, fk := range fks {
+				switch {
+				case include[fk.table] && !include[fk.refTable]:
+					add = append(add, fk.refTable)
+				case include[fk.refTable] && !include[fk.table]:
+					add = append(add, fk.table)
+				}
+			}
+			for _, t := range add {
+				include[t] = true
+			}
+		}
+	}
+
+	d := &diagram{byName: map[string]*diagramTable{}}
+	for _, name := range tables {
+		if !include[name] {
+			continue
+		}
+		t := &diagramTable{name: name, columns: columns[name], primaryKey: map[string]bool{}}
+		for _, c := range primaryKeys[name] {
+			t.primaryKey[c] = true
+		}
+		d.tables = append(d.tables, t)
+		d.byName[name] = t
+	}
+	for _, fk := range fks {
+		if d.byName[fk.table] != nil && d.byName[fk.refTable] != nil {
+			d.fks = append(d.fks, fk)
+		}
+	}
+	return d, nil
+}
+
+// layout sizes the boxes for font, and places them in columns: tables that are referenced
+// to the left of the tables referencing them.
+func (d *diagram) layout(font *draw.Font) {
+	d.lineHeight = font.Height + 2
+	layers := map[string]int{}
+	visiting := map[string]bool{}
+	var layer func(t string) int
+	layer = func(t string) int {
+		if l, ok := layers[t]; ok {
+			return l
+		}
+		if visiting[t] {
+			return 0
+		}
+		visiting[t] = true
+		l := 0
+		for _, fk := range d.fks {
+			if fk.table == t && fk.refTable != t {
+				if rl := layer(fk.refTable) + 1; rl > l {
+					l = rl
+				}
+			}
+		}
+		visiting[t] = false
+		layers[t] = l
+		return l
+	}
+	var columns [][]*diagramTable
+	for _, t := range d.tables {
+		l := layer(t.name)
+		for len(columns) <= l {
+			columns = append(columns, nil)
+		}
+		columns[l] = append(columns[l], t)
+	}
+
+	x := 0
+	for _, col := range columns {
+		sort.Slice(col, func(i, j int) bool {
+			return col[i].name < col[j].name
+		})
+		y := 0
+		width := 0
+		for _, t := range col {
+			w := bold.StringWidth(t.name)
+			for _, c := range t.columns {
+				if cw := font.StringWidth(c.name+"  "+c.dataType) + bold.StringWidth("  "); cw > w {
+					w = cw
+				}
+			}
+			size := image.Pt(w+2*diagramPad, 2*diagramPad+d.lineHeight*(1+len(t.columns))+4)
+			if y > 0 && y+size.Y > diagramMaxHeight {
+				x += width + diagramGapX
+				y = 0
+				width = 0
+			}
+			t.r = image.Rectangle{image.Pt(x, y), image.Pt(x, y).Add(size)}
+			y += size.Y + diagramGapY
+			if size.X > width {
+				width = size.X
+			}
+		}
+		x += width + diagramGapX
+	}
+}
+
+// bounds returns the rectangle enclosing all boxes.
+func (d *diagram) bounds() image.Rectangle {
+	var r image.Rectangle
+	for _, t := range d.tables {
+		r = r.Union(t.r)
+	}
+	return r
+}
+
+// columnY returns the y of the middle of the line of column in the box of table t.
+func (d *diagram) columnY(t *diagramTable, column string) int {
+	y := t.r.Min.Y + diagramPad + d.lineHeight + 4 + d.lineHeight/2
+	for i, c := range t.columns {
+		if c.name == column {
+			return y + i*d.lineHeight
+		}
+	}
+	return t.r.Min.Y + diagramPad + d.lineHeight/2
+}
+
+// draw draws the diagram on img in r, with the diagram origin at offset, scaled by scale.
+// Called from main loop.
+func (d *diagram) draw(img *draw.Image, r image.Rectangle, offset image.Point, scale float64, font *draw.Font) {
+	pt := func(p image.Point) image.Point {
+		return r.Min.Add(offset).Add(image.Pt(int(float64(p.X)*scale), int(float64(p.Y)*scale)))
+	}
+	black := colorImage(draw.Black)
+	line := colorImage(draw.Greyblue)
+	header := colorImage(draw.Palegreygreen)
+	background := colorImage(draw.White)
+
+	for _, fk := range d.fks {
+		a, b := d.byName[fk.table], d.byName[fk.refTable]
+		ay, by := d.columnY(a, fk.columns[0]), d.columnY(b, fk.refColumns[0])
+		var p0, p1 image.Point
+		var midX int
+		switch {
+		case b.r.Min.X >= a.r.Max.X:
+			p0, p1 = image.Pt(a.r.Max.X, ay), image.Pt(b.r.Min.X, by)
+			midX = (p0.X + p1.X) / 2
+		case b.r.Max.X <= a.r.Min.X:
+			p0, p1 = image.Pt(a.r.Min.X, ay), image.Pt(b.r.Max.X, by)
+			midX = (p0.X + p1.X) / 2
+		default:
+			// in the same column, or a reference to itself
+			p0, p1 = image.Pt(a.r.Max.X, ay), image.Pt(b.r.Max.X, by)
+			midX = p0.X
+			if p1.X > midX {
+				midX = p1.X
+			}
+			midX += diagramGapX / 3
+		}
+		m0, m1 := image.Pt(midX, p0.Y), image.Pt(midX, p1.Y)
+		img.Line(pt(p0), pt(m0), 0, 0, 0, line, image.ZP)
+		img.Line(pt(m0), pt(m1), 0, 0, 0, line, image.ZP)
+		drawArrow(img, pt(m1), pt(p1), line)
+	}
+
+	clipr := img.Clipr
+	defer img.ReplClipr(img.Repl, clipr)
+	for _, t := range d.tables {
+		br := image.Rectangle{pt(t.r.Min), pt(t.r.Max)}
+		if !br.Overlaps(clipr) {
+			continue
+		}
+		img.Draw(br, background, nil, image.ZP)
+		hr := image.Rectangle{br.Min, image.Pt(br.Max.X, pt(t.r.Min.Add(image.Pt(0, diagramPad+d.lineHeight+2))).Y)}
+		img.Draw(hr, header, nil, image.ZP)
+		img.Border(br, 1, black, image.ZP)
+
+		img.ReplClipr(img.Repl, br.Inset(1).Intersect(clipr))
+		// rows are placed at scaled positions, like columnY, so they line up with the box and the arrows
+		img.String(pt(t.r.Min.Add(image.Pt(diagramPad, diagramPad))), black, image.ZP, bold, t.name)
+		if scale >= diagramTextScale {
+			for i, c := range t.columns {
+				f := font
+				if t.primaryKey[c.name] {
+					f = bold
+				}
+				p := pt(t.r.Min.Add(image.Pt(diagramPad, diagramPad+d.lineHeight+4+i*d.lineHeight)))
+				q := img.String(p, black, image.ZP, f, c.name)
+				img.String(q.Add(image.Pt(bold.StringWidth("  "), 0)), line, image.ZP, font, c.dataType)
+			}
+		}
+		img.ReplClipr(img.Repl, clipr)
+	}
+}
+
+// dot returns the diagram in the Graphviz dot language.
+func (d *diagram) dot() string {
+	escape := func(s string) string {
+		r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "{", `\{`, "}", `\}`, "|", `\|`, "<", `\<`, ">", `\>`)
+		return r.Replace(s)
+	}
+	port := func(t *diagramTable, column string) int {
+		for i, c := range t.columns {
+			if c.name == column {
+				return i
+			}
+		}
+		return 0
+	}
+	b := &strings.Builder{}
+	fmt.Fprintf(b, "digraph schema {\n\trankdir=RL;\n\tnode [shape=record, fontsize=10];\n")
+	for _, t := range d.tables {
+		fields := []string{escape(t.name)}
+		for i, c := range t.columns {
+			name := escape(c.name)
+			if t.primaryKey[c.name] {
+				name += " (pk)"
+			}
+			fields = append(fields, fmt.Sprintf(`<c%d> %s %s\l`, i, name, escape(c.dataType)))
+		}
+		fmt.Fprintf(b, "\t\"%s\" [label=\"{%s}\"];\n", escape(t.name), strings.Join(fields, "|"))
+	}
+	for _, fk := range d.fks {
+		a, r := d.byName[fk.table], d.byName[fk.refTable]
+		fmt.Fprintf(b, "\t\"%s\":c%d -> \"%s\":c%d [tooltip=\"%s\"];\n", escape(a.name), port(a, fk.columns[0]), escape(r.name), port(r, fk.refColumns[0]), escape(fk.name))
+	}
+	fmt.Fprintf(b, "}\n")
+	return b.String()
+}
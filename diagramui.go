@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"9fans.net/go/draw"
+	"github.com/mjl-/duit"
+)
+
+const (
+	diagramMinZoom = -8
+	diagramMaxZoom = 4
+	diagramMargin  = 20 // around the diagram in exports
+)
+
+// diagramUI shows an ER diagram of the tables in a database, or of a table and its neighbours.
+type diagramUI struct {
+	dbUI *dbUI
+
+	table   *duit.Field // table to center on, all tables if empty
+	levels  *duit.Field // foreign keys to follow from the table
+	path    *duit.Field // for exports, without extension
+	message *duit.Label
+	canvas  *canvasUI
+
+	diagram *diagram
+	zoom    int // scale is 1.25^zoom
+
+	duit.Box
+}
+
+func newDiagramUI(dbUI *dbUI) (ui *diagramUI) {
+	ui = &diagramUI{dbUI: dbUI}
+	ui.table = &duit.Field{Placeholder: "all tables"}
+	ui.levels = &duit.Field{Text: "1"}
+	ui.path = &duit.Field{Text: fmt.Sprintf("%s/%s.%s.diagram", duit.AppDataDir("duitsql"), dbUI.connUI.config.Name, dbUI.dbName)}
+	ui.message = &duit.Label{}
+	ui.canvas = &canvasUI{
+		render: func(img *draw.Image, r image.Rectangle, offset image.Point) {
+			if ui.diagram != nil {
+				ui.diagram.draw(img, r, offset.Add(image.Pt(diagramMargin, diagramMargin)), ui.scale(), dui.Display.DefaultFont)
+			}
+		},
+		zoom: ui.zoomBy,
+	}
+	button := func(text string, fn func()) *duit.Button {
+		return &duit.Button{
+			Text: text,
+			Click: func() (e duit.Event) {
+				fn()
+				return
+			},
+		}
+	}
+	show := button("show", ui.load)
+	show.Colorset = &dui.Primary
+	ui.Box.Kids = duit.NewKids(
+		&duit.Box{
+			Padding: duit.SpaceXY(4, 2),
+			Margin:  image.Pt(4, 2),
+			Valign:  duit.ValignMiddle,
+			Kids: duit.NewKids(
+				label("table"),
+				&duit.Box{Width: 200, Kids: duit.NewKids(ui.table)},
+				label("levels"),
+				&duit.Box{Width: 40, Kids: duit.NewKids(ui.levels)},
+				show,
+				button("-", func() { ui.zoomBy(-1) }),
+				button("+", func() { ui.zoomBy(1) }),
+				button("reset", func() {
+					ui.zoom = 0
+					ui.canvas.offset = image.ZP
+					dui.MarkDraw(ui.canvas)
+				}),
+				&duit.Box{Width: 300, Kids: duit.NewKids(ui.path)},
+				button("save png", ui.savePNG),
+				button("save dot", ui.saveDot),
+				ui.message,
+			),
+		},
+		ui.canvas,
+	)
+	return
+}
+
+func (ui *diagramUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+func (ui *diagramUI) scale() float64 {
+	return math.Pow(1.25, float64(ui.zoom))
+}
+
+// zoomBy zooms in or out by delta steps.
+// Called from main loop.
+func (ui *diagramUI) zoomBy(delta int) {
+	z := ui.zoom + delta
+	if z < diagramMinZoom || z > diagramMaxZoom {
+		return
+	}
+	ui.zoom = z
+	dui.MarkDraw(ui.canvas)
+}
+
+// init shows the diagram of all tables, if nothing was shown yet.
+// Called from main loop.
+func (ui *diagramUI) init() {
+	if ui.diagram == nil && ui.message.Text == "" {
+		ui.load()
+	}
+}
+
+// load reads the tables, columns and keys, and shows the diagram.
+// Called from main loop.
+func (ui *diagramUI) load() {
+	defer ui.layout()
+	focus := strings.TrimSpace(ui.table.Text)
+	levels, err := strconv.Atoi(ui.levels.Text)
+	if err != nil || levels < 0 {
+		ui.message.Text = "error: bad levels"
+		return
+	}
+	ui.message.Text = "reading tables..."
+	connType := ui.dbUI.connUI.config.Type
+	go func() {
+		lcheck, handle := errorHandler(func(err error) {
+			dui.Call <- func() {
+				ui.message.Text = fmt.Sprintf("error: %s", err)
+				ui.layout()
+			}
+		})
+		defer handle()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		tables, columns, err := listColumns(ctx, ui.dbUI.db, connType)
+		lcheck(err, "listing columns")
+		primaryKeys, err := listPrimaryKeys(ctx, ui.dbUI.db, connType)
+		lcheck(err, "listing primary keys")
+		fks, err := listForeignKeys(ctx, ui.dbUI.db, connType, ui.dbUI.dbName)
+		lcheck(err, "listing foreign keys")
+		d, err := newDiagram(tables, columns, primaryKeys, fks, focus, levels)
+		lcheck(err, "making diagram")
+
+		dui.Call <- func() {
+			d.layout(dui.Display.DefaultFont)
+			ui.diagram = d
+			ui.canvas.offset = image.ZP
+			ui.message.Text = fmt.Sprintf("%d tables, %d foreign keys; drag to move, scroll to zoom", len(d.tables), len(d.fks))
+			ui.layout()
+		}
+	}()
+}
+
+// exportPath returns the path for an export with extension ext, creating its directory.
+func (ui *diagramUI) exportPath(ext string) string {
+	p := ui.path.Text + ext
+	os.MkdirAll(path.Dir(p), 0777)
+	return p
+}
+
+// savePNG writes the diagram at its original size as PNG file.
+// Called from main loop.
+func (ui *diagramUI) savePNG() {
+	defer ui.layout()
+	if ui.diagram == nil {
+		ui.message.Text = "no diagram"
+		return
+	}
+	p := ui.exportPath(".png")
+	b := ui.diagram.bounds()
+	size := b.Size().Add(image.Pt(2*diagramMargin, 2*diagramMargin))
+	err := savePNG(p, size, func(img *draw.Image, r image.Rectangle) {
+		ui.diagram.draw(img, r, image.Pt(diagramMargin, diagramMargin).Sub(b.Min), 1, dui.Display.DefaultFont)
+	})
+	if err != nil {
+		ui.message.Text = fmt.Sprintf("error: %s", err)
+	} else {
+		ui.message.Text = "saved " + p
+	}
+}
+
+// saveDot writes the diagram as Graphviz dot file.
+// Called from main loop.
+func (ui *diagramUI) saveDot() {
+	defer ui.layout()
+	if ui.diagram == nil {
+		ui.message.Text = "no diagram"
+		return
+	}
+	p := ui.exportPath(".dot")
+	err := ioutil.WriteFile(p, []byte(ui.diagram.dot()), 0666)
+	if err != nil {
+		ui.message.Text = fmt.Sprintf("error: %s", err)
+	} else {
+		ui.message.Text = "saved " + p
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const dumpBatch = 100 // rows per insert statement in a dump
+
+// foreignKey is a foreign key constraint from columns of table to refColumns of refTable.
+type foreignKey struct {
+	name       string
+	table      string
+	columns    []string
+	refTable   string
+	refColumns []string
+}
+
+// listForeignKeys returns the foreign keys between tables of the database.
+// Table names are as listed by dbUI.
+func listForeignKeys(ctx context.Context, db *sql.DB, connType, dbName string) ([]foreignKey, error) {
+	var q string
+	var args []interface{}
+	switch connType {
+	case "postgres":
+		q = `
+			select c.conname, cn.nspname || '.' || cl.relname, rn.nspname || '.' || rcl.relname, a.attname, ra.attname
+			from pg_constraint c
+			join pg_class cl on c.conrelid = cl.oid
+			join pg_namespace cn on cl.relnamespace = cn.oid
+			join pg_class rcl on c.confrelid = rcl.oid
+			join pg_namespace rn on rcl.relnamespace = rn.oid
+			cross join unnest(c.conkey, c.confkey) with ordinality as k(col, refcol, i)
+			join pg_attribute a on a.attrelid = c.conrelid and a.attnum = k.col
+			join pg_attribute ra on ra.attrelid = c.confrelid and ra.attnum = k.refcol
+			where c.contype = 'f'
+			order by 2, 1, k.i
+		`
+	case "mysql":
+		q = `
+			select constraint_name, table_name, referenced_table_name, column_name, referenced_column_name
+			from information_schema.key_column_usage
+			where table_schema=? and referenced_table_name is not null
+			order by table_name, constraint_name, ordinal_position
+		`
+		args = append(args, dbName)
+	case "sqlserver":
+		q = `
+			select fk.name, concat(schema_name(t.schema_id), '.', t.name), concat(schema_name(rt.schema_id), '.', rt.name), c.name, rc.name
+			from sys.foreign_keys fk
+			join sys.foreign_key_columns fkc on fkc.constraint_object_id = fk.object_id
+			join sys.tables t on fkc.parent_object_id = t.object_id
+			join sys.columns c on c.object_id = fkc.parent_object_id and c.column_id = fkc.parent_column_id
+			join sys.tables rt on fkc.referenced_object_id = rt.object_id
+			join sys.columns rc on rc.object_id = fkc.referenced_object_id and rc.column_id = fkc.referenced_column_id
+			order by 2, 1, fkc.constraint_column_id
+		`
+	default:
+		panic("bad connection type")
+	}
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var fks []foreignKey
+	for rows.Next() {
+		var name, table, refTable, column, refColumn string
+		err = rows.Scan(&name, &table, &refTable, &column, &refColumn)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err)
+		}
+		if n := len(fks); n > 0 && fks[n-1].name == name && fks[n-1].table == table {
+			fks[n-1].columns = append(fks[n-1].columns, column)
+			fks[n-1].refColumns = append(fks[n-1].refColumns, refColumn)
+			continue
+		}
+		fks = append(fks, foreignKey{name, table, []string{column}, refTable, []string{refColumn}})
+	}
+	return fks, rows.Err()
+}
+
+// dependencyOrder returns tables ordered so tables referenced by a foreign key come before the tables referencing them.
+// Reference cycles are broken at the table visited first, in order of name.
+func dependencyOrder(tables []string, fks []foreignKey) []string {
+	refs := map[string][]string{}
+	for _, fk := range fks {
+		if fk.table != fk.refTable {
+			refs[fk.table] = append(refs[fk.table], fk.refTable)
+		}
+	}
+	included := map[string]bool{}
+	for _, t := range tables {
+		included[t] = true
+	}
+	sorted := append([]string{}, tables...)
+	sort.Strings(sorted)
+
+	var order []string
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var visit func(t string)
+	visit = func(t string) {
+		if state[t] != 0 {
+			return
+		}
+		state[t] = visiting
+		for _, r := range refs[t] {
+			if included[r] {
+				visit(r)
+			}
+		}
+		state[t] = done
+		order = append(order, t)
+	}
+	for _, t := range sorted {
+		visit(t)
+	}
+	return order
+}
+
+// writeDump writes create table statements, insert statements and foreign keys for tables to w, in dependency order.
+// progress is called before dumping each table.
+// Called from outside main loop.
+func writeDump(ctx context.Context, w io.Writer, db *sql.DB, connType, dbName string, tables []string, progress func(index int, table string)) (err error) {
+	lcheck, handle := errorHandler(func(e error) {
+		err = e
+	})
+	defer handle()
+
+	fks, err := listForeignKeys(ctx, db, connType, dbName)
+	lcheck(err, "listing foreign keys")
+	tables = dependencyOrder(tables, fks)
+
+	var separator string
+	if connType == "sqlserver" {
+		separator = "go\n"
+	}
+	write := func(format string, args ...interface{}) {
+		_, err := fmt.Fprintf(w, format, args...)
+		lcheck(err, "writing")
+	}
+	write("-- dump of %s database %s, %d tables, created %s\n\n", connType, dbName, len(tables), time.Now().Format(time.RFC3339))
+
+	dumpTable := func(table string) {
+		columns, primaryKey, err := loadTableColumns(ctx, db, connType, dbName, table)
+		lcheck(err, "listing columns of "+table)
+		write("%s;\n%s\n", createTableSQL(connType, connType, table, columns, primaryKey), separator)
+
+		names := make([]string, len(columns))
+		for j, c := range columns {
+			names[j] = quoteIdent(connType, c.name)
+		}
+		insert := fmt.Sprintf("insert into %s (%s) values", quoteIdent(connType, table), strings.Join(names, ", "))
+		rows, err := db.QueryContext(ctx, fmt.Sprintf("select %s from %s", strings.Join(names, ", "), quoteIdent(connType, table)))
+		lcheck(err, "reading rows of "+table)
+		defer rows.Close()
+		colTypes, err := rows.ColumnTypes()
+		lcheck(err, "reading column types")
+		kinds := make([]valueKind, len(colTypes))
+		for j, ct := range colTypes {
+			kinds[j] = columnKind(connType, ct.DatabaseTypeName())
+		}
+		n := 0
+		for rows.Next() {
+			values := make([]interface{}, len(columns))
+			dest := make([]interface{}, len(columns))
+			for j := range values {
+				dest[j] = &values[j]
+			}
+			err = rows.Scan(dest...)
+			lcheck(err, "scanning row")
+			literals := make([]string, len(values))
+			for j, v := range values {
+				literals[j] = sqlLiteral(connType, kinds[j], v)
+			}
+			if n%dumpBatch == 0 {
+				if n > 0 {
+					write(";\n")
+				}
+				write("%s\n", insert)
+			} else {
+				write(",\n")
+			}
+			write("(%s)", strings.Join(literals, ", "))
+			n++
+		}
+		lcheck(rows.Err(), "reading rows of "+table)
+		if n > 0 {
+			write(";\n%s", separator)
+		}
+		write("\n")
+	}
+	for i, table := range tables {
+		progress(i, table)
+		dumpTable(table)
+	}
+
+	included := map[string]bool{}
+	for _, t := range tables {
+		included[t] = true
+	}
+	for _, fk := range fks {
+		if !included[fk.table] || !included[fk.refTable] {
+			continue
+		}
+		quote := func(l []string) string {
+			r := make([]string, len(l))
+			for i, s := range l {
+				r[i] = quoteIdent(connType, s)
+			}
+			return strings.Join(r, ", ")
+		}
+		write("alter table %s add constraint %s foreign key (%s) references %s (%s);\n%s", quoteIdent(connType, fk.table), quoteIdent(connType, fk.name), quote(fk.columns), quoteIdent(connType, fk.refTable), quote(fk.refColumns), separator)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/mjl-/duit"
+)
+
+// dumpUI writes tables of a database with their data to a .sql file, and restores such files by running their statements.
+type dumpUI struct {
+	dbUI *dbUI
+
+	tables      *duit.Gridlist // tables to dump, all if none selected
+	dumpPath    *duit.Field
+	restorePath *duit.Field
+	stopOnError *duit.Checkbox
+	message     *duit.Label
+	errors      *duit.Gridlist
+
+	cancel context.CancelFunc // cancels the running dump or restore, nil if none
+
+	duit.Box
+}
+
+func newDumpUI(dbUI *dbUI) (ui *dumpUI) {
+	ui = &dumpUI{dbUI: dbUI}
+	defaultPath := fmt.Sprintf("%s/%s.%s.dump.sql", duit.AppDataDir("duitsql"), dbUI.connUI.config.Name, dbUI.dbName)
+	ui.tables = &duit.Gridlist{
+		Header:   &duit.Gridrow{Values: []string{"table"}},
+		Multiple: true,
+		Striped:  true,
+		Padding:  duit.SpaceXY(4, 2),
+	}
+	ui.dumpPath = &duit.Field{Text: defaultPath}
+	ui.restorePath = &duit.Field{Text: defaultPath}
+	ui.stopOnError = &duit.Checkbox{Checked: true}
+	ui.message = &duit.Label{}
+	ui.errors = &duit.Gridlist{
+		Header:  &duit.Gridrow{Values: []string{"statement", "error"}},
+		Halign:  []duit.Halign{duit.HalignLeft, duit.HalignLeft},
+		Striped: true,
+		Padding: duit.SpaceXY(4, 2),
+	}
+
+	dump := &duit.Button{
+		Text:     "dump",
+		Colorset: &dui.Primary,
+		Click: func() (e duit.Event) {
+			ui.dump()
+			return
+		},
+	}
+	restore := &duit.Button{
+		Text: "restore",
+		Click: func() (e duit.Event) {
+			ui.restore()
+			return
+		},
+	}
+	cancel := &duit.Button{
+		Text: "cancel",
+		Click: func() (e duit.Event) {
+			if ui.cancel != nil {
+				ui.cancel()
+			}
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(
+		toolbar(
+			label("dump selected tables (or all) to"),
+			&duit.Box{Width: 400, Kids: duit.NewKids(ui.dumpPath)},
+			dump,
+		),
+		toolbar(
+			label("restore from"),
+			&duit.Box{Width: 400, Kids: duit.NewKids(ui.restorePath)},
+			ui.stopOnError,
+			label("stop on error"),
+			restore,
+		),
+		toolbar(cancel, ui.message),
+		&duit.Split{
+			Gutter:     1,
+			Background: dui.Gutter,
+			Split: func(width int) []int {
+				first := width / 3
+				return []int{first, width - first}
+			},
+			Kids: duit.NewKids(duit.NewScroll(ui.tables), duit.NewScroll(ui.errors)),
+		},
+	)
+	return
+}
+
+func (ui *dumpUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+// listTables lists the tables of the database, keeping the selection.
+// Called from main loop.
+func (ui *dumpUI) listTables() {
+	defer ui.layout()
+	selected := map[string]bool{}
+	for _, row := range ui.tables.Rows {
+		selected[row.Values[0]] = row.Selected
+	}
+	var rows []*duit.Gridrow
+	walkObjects(ui.dbUI.tree, func(n *objectNode) {
+		if n.object.Kind == "T" {
+			rows = append(rows, &duit.Gridrow{
+				Selected: selected[n.object.Name],
+				Values:   []string{n.object.Name},
+			})
+		}
+	})
+	ui.tables.Rows = rows
+}
+
+// dump starts writing the selected tables, or all tables if none are selected, to the dump file.
+// Called from main loop.
+func (ui *dumpUI) dump() {
+	defer ui.layout()
+	if ui.cancel != nil {
+		ui.message.Text = "dump or restore already in progress"
+		return
+	}
+	var tables, all []string
+	for _, row := range ui.tables.Rows {
+		all = append(all, row.Values[0])
+		if row.Selected {
+			tables = append(tables, row.Values[0])
+		}
+	}
+	if len(tables) == 0 {
+		tables = all
+	}
+	if len(tables) == 0 {
+		ui.message.Text = "no tables to dump"
+		return
+	}
+	dumpPath := ui.dumpPath.Text
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancel = cancel
+	ui.errors.Rows = nil
+	ui.message.Text = "dumping..."
+
+	connType := ui.dbUI.connUI.config.Type
+	go func() {
+		defer cancel()
+		lcheck, handle := errorHandler(func(err error) {
+			dui.Call <- func() {
+				ui.cancel = nil
+				ui.message.Text = fmt.Sprintf("error: %s", err)
+				ui.layout()
+			}
+		})
+		defer handle()
+
+		// written to a temporary file first, so a failed dump does not replace an earlier one
+		os.MkdirAll(path.Dir(dumpPath), 0777)
+		f, err := ioutil.TempFile(path.Dir(dumpPath), path.Base(dumpPath)+".tmp")
+		lcheck(err, "creating file")
+		renamed := false
+		defer func() {
+			f.Close()
+			if !renamed {
+				os.Remove(f.Name())
+			}
+		}()
+		w := bufio.NewWriter(f)
+		err = writeDump(ctx, w, ui.dbUI.db, connType, ui.dbUI.dbName, tables, func(index int, table string) {
+			msg := fmt.Sprintf("dumping %s, table %d of %d...", table, index+1, len(tables))
+			dui.Call <- func() {
+				ui.message.Text = msg
+				ui.layout()
+			}
+		})
+		lcheck(err, "dumping")
+		err = w.Flush()
+		lcheck(err, "writing file")
+		err = f.Close()
+		lcheck(err, "closing file")
+		err = os.Rename(f.Name(), dumpPath)
+		lcheck(err, "renaming file")
+		renamed = true
+
+		dui.Call <- func() {
+			ui.cancel = nil
+			ui.message.Text = fmt.Sprintf("dumped %d tables to %s", len(tables), dumpPath)
+			ui.layout()
+		}
+	}()
+}
+
+// restore starts running the statements of the restore file, refreshing the objects of the database when done.
+// Called from main loop.
+func (ui *dumpUI) restore() {
+	defer ui.layout()
+	if ui.cancel != nil {
+		ui.message.Text = "dump or restore already in progress"
+		return
+	}
+	restorePath := ui.restorePath.Text
+	stopOnError := ui.stopOnError.Checked
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancel = cancel
+	ui.errors.Rows = nil
+	ui.message.Text = "reading file..."
+
+	connType := ui.dbUI.connUI.config.Type
+	go func() {
+		defer cancel()
+		lcheck, handle := errorHandler(func(err error) {
+			dui.Call <- func() {
+				ui.cancel = nil
+				ui.message.Text = fmt.Sprintf("error: %s", err)
+				ui.layout()
+			}
+		})
+		defer handle()
+
+		buf, err := ioutil.ReadFile(restorePath)
+		lcheck(err, "reading file")
+		statements := splitStatements(connType, string(buf))
+		var failed int
+		executed := runScript(ctx, ui.dbUI.db, statements, func(index int) {
+			if index%10 != 0 {
+				return
+			}
+			msg := fmt.Sprintf("running statement %d of %d...", index+1, len(statements))
+			dui.Call <- func() {
+				ui.message.Text = msg
+				ui.layout()
+			}
+		}, func(index int, err error) bool {
+			failed++
+			row := &duit.Gridrow{Values: []string{fmt.Sprintf("%d: %s", index+1, oneLine(firstLine(statements[index]))), err.Error()}}
+			dui.Call <- func() {
+				ui.errors.Rows = append(ui.errors.Rows, row)
+				ui.layout()
+			}
+			return !stopOnError
+		})
+
+		canceled := ctx.Err() == context.Canceled
+		dui.Call <- func() {
+			ui.cancel = nil
+			msg := fmt.Sprintf("executed %d of %d statements", executed, len(statements))
+			if failed > 0 {
+				msg += fmt.Sprintf(", %d failed", failed)
+			}
+			if canceled {
+				msg += ", canceled"
+			}
+			ui.message.Text = msg
+			ui.layout()
+			ui.dbUI.refreshObjects()
+		}
+	}()
+}
+
+// firstLine returns the first line of s.
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
@@ -3,11 +3,13 @@ package main
 import (
 	"bytes"
 	"fmt"
+	"image"
 	"io"
 	"io/ioutil"
 	"log"
 	"os"
 	"path"
+	"strings"
 
 	"9fans.net/go/draw"
 	"github.com/mjl-/duit"
@@ -18,6 +20,8 @@ type editUI struct {
 
 	sqlPath   string
 	edit      *duit.Edit
+	runOn     *duit.Buttongroup // this database, all databases, or those matching databases
+	databases *duit.Field       // comma-separated names or like-patterns
 	resultBox *duit.Box
 
 	duit.Box
@@ -76,9 +80,13 @@ func newEditUI(dbUI *dbUI) (ui *editUI) {
 			q := string(query)
 			log.Printf("query is %q\n", q)
 			defer ui.layout()
+			if ui.runOn.Selected > 0 {
+				ui.runMulti(q)
+				break
+			}
 			tabUI := newResultUI(ui.dbUI, q)
 			ui.resultBox.Kids = duit.NewKids(tabUI)
-			go tabUI.load()
+			go tabUI.load(tabUI.compute)
 
 		case draw.KeyCmd + 's':
 			os.MkdirAll(path.Dir(ui.sqlPath), 0777)
@@ -109,21 +117,70 @@ func newEditUI(dbUI *dbUI) (ui *editUI) {
 		sqlPath:   sqlPath,
 		edit:      edit,
 		resultBox: resultBox,
-		Box: duit.Box{
-			Kids: duit.NewKids(
-				&duit.Split{
-					Vertical:   true,
-					Gutter:     1,
-					Background: dui.Gutter,
-					Split: func(height int) []int {
-						half := height / 2
-						return []int{half, height - half}
-					},
-					Kids: duit.NewKids(edit, resultBox),
+	}
+	ui.runOn = &duit.Buttongroup{
+		Texts: []string{"this database", "all databases", "matching"},
+	}
+	ui.databases = &duit.Field{Placeholder: "names or like-patterns, comma-separated"}
+	ui.Box = duit.Box{
+		Kids: duit.NewKids(
+			&duit.Box{
+				Padding: duit.SpaceXY(4, 2),
+				Margin:  image.Pt(4, 2),
+				Valign:  duit.ValignMiddle,
+				Kids: duit.NewKids(
+					label("run on"),
+					ui.runOn,
+					&duit.Box{Width: 300, Kids: duit.NewKids(ui.databases)},
+				),
+			},
+			&duit.Split{
+				Vertical:   true,
+				Gutter:     1,
+				Background: dui.Gutter,
+				Split: func(height int) []int {
+					half := height / 2
+					return []int{half, height - half}
 				},
-			),
-		},
+				Kids: duit.NewKids(edit, resultBox),
+			},
+		),
 	}
-	ui.Box.Kids[0].ID = "edit"
+	ui.Box.Kids[1].ID = "edit"
 	return
 }
+
+// runMulti runs query on all databases of the connection, or those matching the databases field.
+// Called from main loop.
+func (ui *editUI) runMulti(query string) {
+	var patterns []string
+	if ui.runOn.Selected == 2 {
+		for _, p := range strings.Split(ui.databases.Text, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				patterns = append(patterns, p)
+			}
+		}
+		if len(patterns) == 0 {
+			ui.resultBox.Kids = duit.NewKids(middle(label("enter the databases to run on")))
+			return
+		}
+	}
+	var names []string
+	for _, lv := range ui.dbUI.connUI.databases.Values {
+		name := lv.Value.(*dbUI).dbName
+		match := patterns == nil
+		for _, p := range patterns {
+			match = match || likeMatch(p, name)
+		}
+		if match {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		ui.resultBox.Kids = duit.NewKids(middle(label("no matching databases")))
+		return
+	}
+	mUI := newMultiResultUI(ui.dbUI, names, query)
+	ui.resultBox.Kids = duit.NewKids(mUI)
+	mUI.run()
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"image"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"9fans.net/go/draw"
+	"github.com/mjl-/duit"
+)
+
+const (
+	findRowLimit = 1000             // maximum number of matching rows counted per table
+	findTimeout  = 30 * time.Second // per table
+)
+
+var guidRegexp = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// findColumn is a column of a table, as searched by findDataUI.
+type findColumn struct {
+	name     string
+	dataType string // from information_schema, lower case
+}
+
+// findMatch is a column in a table with rows holding the value.
+type findMatch struct {
+	table  string
+	column string
+}
+
+// findDataUI searches all (or some) tables of a database for rows with a value in a column.
+type findDataUI struct {
+	dbUI     *dbUI
+	value    *duit.Field
+	tables   *duit.Field // only tables with names containing this text, all if empty
+	contains *duit.Checkbox
+	message  *duit.Label
+	grid     *duit.Gridlist
+	cancel   context.CancelFunc // cancels the running search, nil if none
+	gen      int                // incremented for each search, progress of older searches is ignored
+
+	duit.Box
+}
+
+func newFindDataUI(dbUI *dbUI) (ui *findDataUI) {
+	ui = &findDataUI{dbUI: dbUI}
+	ui.message = &duit.Label{}
+	ui.value = &duit.Field{
+		Placeholder: "value...",
+		Keys: func(k rune, m draw.Mouse) (e duit.Event) {
+			if k == '\n' {
+				e.Consumed = true
+				ui.find()
+			}
+			return
+		},
+	}
+	ui.tables = &duit.Field{Placeholder: "all tables"}
+	ui.contains = &duit.Checkbox{}
+	ui.grid = &duit.Gridlist{
+		Header:  &duit.Gridrow{Values: []string{"table", "column", "rows"}},
+		Halign:  []duit.Halign{duit.HalignLeft, duit.HalignLeft, duit.HalignRight},
+		Striped: true,
+		Padding: duit.SpaceXY(4, 2),
+		Changed: func(index int) (e duit.Event) {
+			row := ui.grid.Rows[index]
+			if row.Selected {
+				ui.open(row.Value.(findMatch))
+			}
+			return
+		},
+	}
+	find := &duit.Button{
+		Text:     "find",
+		Colorset: &dui.Primary,
+		Click: func() (e duit.Event) {
+			ui.find()
+			return
+		},
+	}
+	cancel := &duit.Button{
+		Text: "cancel",
+		Click: func() (e duit.Event) {
+			if ui.cancel != nil {
+				ui.cancel()
+			}
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(
+		&duit.Box{
+			Padding: duit.SpaceXY(4, 2),
+			Margin:  image.Pt(4, 2),
+			Valign:  duit.ValignMiddle,
+			Kids: duit.NewKids(
+				&duit.Box{Width: 200, Kids: duit.NewKids(ui.value)},
+				label("in tables containing"),
+				&duit.Box{Width: 150, Kids: duit.NewKids(ui.tables)},
+				ui.contains,
+				label("match text containing value"),
+				find,
+				cancel,
+				ui.message,
+			),
+		},
+		duit.NewScroll(ui.grid),
+	)
+	return
+}
+
+func (ui *findDataUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+// open shows the table of the match, filtered to rows with the value.
+// Called from main loop.
+func (ui *findDataUI) open(m findMatch) {
+	value := ui.value.Text
+	ui.dbUI.selectObject("T", m.table)
+	for _, k := range ui.dbUI.contentUI.Kids {
+		if tUI, ok := k.UI.(*tableUI); ok && tUI.resultUI != nil {
+			tUI.resultUI.setFilter(value)
+		}
+	}
+}
+
+// compatible returns whether a column of type dataType can hold value, or text containing it.
+func compatible(dataType, value string, contains bool) bool {
+	switch {
+	case strings.Contains(dataType, "char") || strings.Contains(dataType, "text"):
+		return true
+	case contains:
+		return false
+	case dataType == "uuid" || dataType == "uniqueidentifier":
+		return guidRegexp.MatchString(value)
+	case intType(dataType):
+		_, err := strconv.ParseInt(value, 10, 64)
+		return err == nil
+	case numericType(dataType):
+		_, err := strconv.ParseFloat(value, 64)
+		return err == nil
+	}
+	return false
+}
+
+// intType returns whether columns of type dataType hold integers.
+func intType(dataType string) bool {
+	switch dataType {
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint":
+		return true
+	}
+	return false
+}
+
+// numericType returns whether columns of type dataType hold numbers.
+func numericType(dataType string) bool {
+	switch dataType {
+	case "numeric", "decimal", "real", "double precision", "double", "float", "money":
+		return true
+	}
+	return intType(dataType)
+}
+
+// find starts searching the tables for the value, canceling a running search.
+// Called from main loop.
+func (ui *findDataUI) find() {
+	defer ui.layout()
+	if ui.cancel != nil {
+		ui.cancel()
+	}
+	value := ui.value.Text
+	if value == "" {
+		ui.message.Text = "enter a value to find"
+		return
+	}
+	contains := ui.contains.Checked
+	tableFilter := strings.ToLower(ui.tables.Text)
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancel = cancel
+	ui.gen++
+	gen := ui.gen
+	ui.grid.Rows = nil
+	ui.message.Text = "listing columns..."
+
+	go func() {
+		defer cancel()
+		lcheck, handle := errorHandler(func(err error) {
+			dui.Call <- func() {
+				if ui.gen != gen {
+					return
+				}
+				ui.cancel = nil
+				ui.message.Text = fmt.Sprintf("error: %s", err)
+				ui.layout()
+			}
+		})
+		defer handle()
+
+		tables, columns, err := listColumns(ctx, ui.dbUI.db, ui.dbUI.connUI.config.Type)
+		lcheck(err, "listing columns")
+
+		var searched int
+		var failed []string
+		for i, table := range tables {
+			if tableFilter != "" && !strings.Contains(strings.ToLower(table), tableFilter) {
+				continue
+			}
+			var cols []findColumn
+			for _, c := range columns[table] {
+				if compatible(c.dataType, value, contains) {
+					cols = append(cols, c)
+				}
+			}
+			if len(cols) == 0 {
+				continue
+			}
+			if ctx.Err() != nil {
+				break
+			}
+			msg := fmt.Sprintf("searching %s, table %d of %d...", table, i+1, len(tables))
+			dui.Call <- func() {
+				if ui.gen != gen {
+					return
+				}
+				ui.message.Text = msg
+				ui.layout()
+			}
+			searched++
+			counts, more, err := ui.findInTable(ctx, table, cols, value, contains)
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("%s: %s", table, oneLine(err.Error())))
+				continue
+			}
+			var rows []*duit.Gridrow
+			for j, c := range cols {
+				if counts[j] == 0 {
+					continue
+				}
+				n := fmt.Sprintf("%d", counts[j])
+				if more {
+					n += "+"
+				}
+				rows = append(rows, &duit.Gridrow{
+					Values: []string{table, c.name, n},
+					Value:  findMatch{table, c.name},
+				})
+			}
+			if len(rows) > 0 {
+				dui.Call <- func() {
+					if ui.gen != gen {
+						return
+					}
+					ui.grid.Rows = append(ui.grid.Rows, rows...)
+					ui.layout()
+				}
+			}
+		}
+
+		canceled := ctx.Err() == context.Canceled
+		dui.Call <- func() {
+			if ui.gen != gen {
+				return
+			}
+			ui.cancel = nil
+			msg := fmt.Sprintf("%d matches in %d tables searched", len(ui.grid.Rows), searched)
+			if len(failed) > 0 {
+				msg += fmt.Sprintf(", %d tables failed: %s", len(failed), strings.Join(failed, "; "))
+			}
+			if canceled {
+				msg += ", canceled"
+			}
+			ui.message.Text = msg
+			ui.layout()
+		}
+	}()
+}
+
+// listColumns returns the tables in the database with their columns.
+// Called from outside main loop.
+func listColumns(ctx context.Context, db *sql.DB, connType string) (tables []string, columns map[string][]findColumn, err error) {
+	var q string
+	switch connType {
+	case "postgres":
+		q = `
+			select c.table_schema || '.' || c.table_name, c.column_name, c.data_type
+			from information_schema.columns c
+			join information_schema.tables t on c.table_schema = t.table_schema and c.table_name = t.table_name
+			where t.table_type = 'BASE TABLE' and c.table_schema not in ('pg_catalog', 'information_schema')
+			order by 1, c.ordinal_position
+		`
+	case "mysql":
+		q = `
+			select c.table_name, c.column_name, c.data_type
+			from information_schema.columns c
+			join information_schema.tables t on c.table_schema = t.table_schema and c.table_name = t.table_name
+			where t.table_type = 'BASE TABLE' and c.table_schema = database()
+			order by 1, c.ordinal_position
+		`
+	case "sqlserver":
+		q = `
+			select concat(c.table_schema, '.', c.table_name), c.column_name, c.data_type
+			from information_schema.columns c
+			join information_schema.tables t on c.table_schema = t.table_schema and c.table_name = t.table_name
+			where t.table_type = 'BASE TABLE'
+			order by 1, c.ordinal_position
+		`
+	default:
+		panic("bad connection type")
+	}
+
+	rows, err := db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	columns = map[string][]findColumn{}
+	for rows.Next() {
+		var table string
+		var c findColumn
+		err = rows.Scan(&table, &c.name, &c.dataType)
+		if err != nil {
+			return nil, nil, fmt.Errorf("scanning row: %s", err)
+		}
+		c.dataType = strings.ToLower(c.dataType)
+		if _, ok := columns[table]; !ok {
+			tables = append(tables, table)
+		}
+		columns[table] = append(columns[table], c)
+	}
+	return tables, columns, rows.Err()
+}
+
+// findInTable counts the rows with value in each of the columns, looking at no more than findRowLimit matching rows.
+// more indicates the limit was reached.
+// Called from outside main loop.
+func (ui *findDataUI) findInTable(ctx context.Context, table string, cols []findColumn, value string, contains bool) (counts []int64, more bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, findTimeout)
+	defer cancel()
+
+	connType := ui.dbUI.connUI.config.Type
+	var args []interface{}
+	if contains {
+		value = "%" + value + "%"
+	}
+	// condition returns the comparison of column with the value, mysql needs a parameter for each comparison
+	condition := func(c findColumn) string {
+		col := quoteIdent(connType, c.name)
+		var param string
+		switch connType {
+		case "postgres":
+			param = "$1"
+			if len(args) == 0 {
+				args = append(args, value)
+			}
+		case "mysql":
+			param = "?"
+			args = append(args, value)
+		case "sqlserver":
+			param = "@value"
+			if len(args) == 0 {
+				args = append(args, sql.Named("value", value))
+			}
+		}
+		if connType == "postgres" {
+			// $1 gets a single type, so compare as text, and numbers by value
+			switch {
+			case contains:
+				return col + "::text ilike " + param + "::text"
+			case numericType(c.dataType):
+				return col + "::numeric = " + param + "::numeric"
+			}
+			return col + "::text = " + param + "::text"
+		}
+		if contains {
+			return col + " like " + param
+		}
+		return col + " = " + param
+	}
+
+	var selectCols, conds []string
+	for _, c := range cols {
+		selectCols = append(selectCols, quoteIdent(connType, c.name))
+		conds = append(conds, condition(c))
+	}
+	subquery := fmt.Sprintf("select %s from %s where %s", strings.Join(selectCols, ", "), quoteIdent(connType, table), strings.Join(conds, " or "))
+	if connType == "sqlserver" {
+		subquery = fmt.Sprintf("select top %d %s", findRowLimit, strings.TrimPrefix(subquery, "select "))
+	} else {
+		subquery += fmt.Sprintf(" limit %d", findRowLimit)
+	}
+	sums := []string{"count(*)"}
+	for _, c := range cols {
+		sums = append(sums, fmt.Sprintf("sum(case when %s then 1 else 0 end)", condition(c)))
+	}
+	q := fmt.Sprintf("select %s from (%s) x", strings.Join(sums, ", "), subquery)
+
+	values := make([]sql.NullInt64, 1+len(cols))
+	dest := make([]interface{}, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	err = ui.dbUI.db.QueryRowContext(ctx, q, args...).Scan(dest...)
+	if err != nil {
+		return nil, false, err
+	}
+	counts = make([]int64, len(cols))
+	for i := range cols {
+		counts[i] = values[1+i].Int64
+	}
+	return counts, values[0].Int64 >= findRowLimit, nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formatConfig holds the settings for showing values in result grids, stored with each connection config.
+// Zero values mean the defaults.
+type formatConfig struct {
+	TimeFormat string // Go time layout, eg "2006-01-02 15:04:05"
+	TimeZone   string // Location to show times in, eg "UTC" or "Local". Empty keeps the zone as returned.
+	Precision  int    // If > 0, number of digits after the decimal point for floats and numerics.
+	True       string // Text for boolean true, default "true".
+	False      string // Text for boolean false, default "false".
+	Null       string // Text for NULL, default "NULL". A string with the same text is shown quoted.
+}
+
+const defaultTimeFormat = "2006-01-02 15:04:05.999999999 -07:00"
+
+// valueKind is how the values of a column are interpreted before formatting.
+type valueKind int
+
+const (
+	kindOther valueKind = iota
+	kindText
+	kindBinary
+	kindNumeric // returned as text by the driver
+	kindTime    // returned as text by the driver (mysql)
+	kindArray   // postgres array literal
+	kindRecord  // postgres composite/record literal
+	kindGUID    // sqlserver uniqueidentifier
+)
+
+// columnKind determines the value kind from the database type name returned by the driver of connection type connType.
+func columnKind(connType, dbType string) valueKind {
+	switch connType {
+	case "postgres":
+		switch {
+		case dbType == "BYTEA":
+			return kindBinary
+		case dbType == "NUMERIC":
+			return kindNumeric
+		case dbType == "RECORD":
+			return kindRecord
+		case strings.HasPrefix(dbType, "_"):
+			return kindArray
+		}
+	case "mysql":
+		switch dbType {
+		case "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BINARY", "VARBINARY", "BIT", "GEOMETRY":
+			return kindBinary
+		case "DECIMAL", "FLOAT", "DOUBLE":
+			return kindNumeric
+		case "DATETIME", "TIMESTAMP", "DATE":
+			return kindTime
+		}
+	case "sqlserver":
+		switch dbType {
+		case "BINARY", "VARBINARY", "IMAGE", "TIMESTAMP":
+			return kindBinary
+		case "DECIMAL", "NUMERIC", "MONEY", "SMALLMONEY":
+			return kindNumeric
+		case "UNIQUEIDENTIFIER":
+			return kindGUID
+		}
+	}
+	switch dbType {
+	case "TEXT", "VARCHAR", "CHAR", "NVARCHAR", "NCHAR", "NTEXT", "BPCHAR", "JSON", "JSONB", "XML":
+		return kindText
+	}
+	return kindOther
+}
+
+// valueFormatter turns raw values as scanned from the database into text for the grid.
+type valueFormatter struct {
+	config formatConfig
+	loc    *time.Location // nil keeps the zone as returned
+}
+
+func newValueFormatter(config formatConfig) *valueFormatter {
+	f := &valueFormatter{config: config}
+	if f.config.TimeFormat == "" {
+		f.config.TimeFormat = defaultTimeFormat
+	}
+	if f.config.True == "" {
+		f.config.True = "true"
+	}
+	if f.config.False == "" {
+		f.config.False = "false"
+	}
+	if f.config.Null == "" {
+		f.config.Null = "NULL"
+	}
+	if config.TimeZone != "" {
+		loc, err := time.LoadLocation(config.TimeZone)
+		if err == nil {
+			f.loc = loc
+		}
+	}
+	return f
+}
+
+// format returns the text for v, a value of a column of kind.
+func (f *valueFormatter) format(kind valueKind, v interface{}) string {
+	switch vv := v.(type) {
+	case nil:
+		return f.config.Null
+	case bool:
+		if vv {
+			return f.config.True
+		}
+		return f.config.False
+	case float32:
+		return f.float(float64(vv))
+	case float64:
+		return f.float(vv)
+	case time.Time:
+		return f.time(vv)
+	case string:
+		return f.text(kind, vv)
+	case []byte:
+		switch kind {
+		case kindBinary:
+			return fmt.Sprintf("%x", vv)
+		case kindGUID:
+			return formatGUID(vv)
+		}
+		return f.text(kind, string(vv))
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+func (f *valueFormatter) text(kind valueKind, s string) string {
+	switch kind {
+	case kindNumeric:
+		return f.numeric(s)
+	case kindTime:
+		for _, layout := range []string{"2006-01-02 15:04:05.999999999", "2006-01-02"} {
+			if t, err := time.Parse(layout, s); err == nil {
+				// mysql returns times without zone, we only change the format.
+				return t.Format(f.config.TimeFormat)
+			}
+		}
+	case kindArray, kindRecord:
+		if l, ok := parsePGList(s); ok {
+			strs := make([]string, len(l))
+			for i, e := range l {
+				if e == nil {
+					strs[i] = f.config.Null
+				} else {
+					strs[i] = *e
+				}
+			}
+			if kind == kindArray {
+				return "[" + strings.Join(strs, ", ") + "]"
+			}
+			return "(" + strings.Join(strs, ", ") + ")"
+		}
+	}
+	if s == f.config.Null {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func (f *valueFormatter) float(v float64) string {
+	if f.config.Precision > 0 {
+		return strconv.FormatFloat(v, 'f', f.config.Precision, 64)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// numeric rounds a decimal number in text form without going through a float.
+func (f *valueFormatter) numeric(s string) string {
+	if f.config.Precision <= 0 {
+		return s
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return s
+	}
+	return r.FloatString(f.config.Precision)
+}
+
+func (f *valueFormatter) time(t time.Time) string {
+	if f.loc != nil {
+		t = t.In(f.loc)
+	}
+	return t.Format(f.config.TimeFormat)
+}
+
+// formatGUID formats a sqlserver uniqueidentifier, of which the first three groups are stored little-endian.
+func formatGUID(b []byte) string {
+	if len(b) != 16 {
+		return fmt.Sprintf("%x", b)
+	}
+	return fmt.Sprintf("%X-%X-%X-%X-%X", []byte{b[3], b[2], b[1], b[0]}, []byte{b[5], b[4]}, []byte{b[7], b[6]}, b[8:10], b[10:])
+}
+
+// parsePGList parses the elements of a postgres array literal like {a,"b c",NULL} or record literal like (1,"x y",).
+// Nested arrays are returned as their literal text. NULL elements are nil.
+func parsePGList(s string) ([]*string, bool) {
+	if len(s) < 2 || !(s[0] == '{' && s[len(s)-1] == '}' || s[0] == '(' && s[len(s)-1] == ')') {
+		return nil, false
+	}
+	isArray := s[0] == '{'
+	s = s[1 : len(s)-1]
+	var l []*string
+	if s == "" {
+		return l, true
+	}
+	for {
+		var b strings.Builder
+		quoted, wasQuoted := false, false
+		depth := 0
+		i := 0
+	Element:
+		for ; i < len(s); i++ {
+			c := s[i]
+			switch {
+			case c == '"' && depth > 0:
+			case c == '"' && quoted && !isArray && i+1 < len(s) && s[i+1] == '"':
+				// doubled quote in record element
+				i++
+			case c == '"':
+				quoted = !quoted
+				wasQuoted = true
+				continue
+			case c == '\\' && i+1 < len(s):
+				i++
+				c = s[i]
+			case quoted:
+			case c == '{':
+				depth++
+			case c == '}':
+				depth--
+			case c == ',' && depth == 0:
+				break Element
+			}
+			b.WriteByte(c)
+		}
+		e := b.String()
+		if !wasQuoted && (isArray && e == "NULL" || !isArray && e == "") {
+			l = append(l, nil)
+		} else {
+			l = append(l, &e)
+		}
+		if i >= len(s) {
+			break
+		}
+		s = s[i+1:]
+	}
+	return l, true
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// generators for generateUI, "default" leaves the column out of the insert
+var generatorKinds = []string{"default", "sequence", "random", "list", "lorem", "date", "foreign key"}
+
+// generatorHints are placeholders for the parameters of generatorKinds.
+var generatorHints = map[string]string{
+	"default":     "",
+	"sequence":    "start, default 1",
+	"random":      "min,max",
+	"list":        "value,value,...",
+	"lorem":       "min,max words, default 3,10",
+	"date":        "from,to, default last year",
+	"foreign key": "",
+}
+
+var loremWords = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod tempor incididunt ut labore et dolore magna aliqua ut enim ad minim veniam quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat duis aute irure dolor in reprehenderit in voluptate velit esse cillum dolore eu fugiat nulla pariatur excepteur sint occaecat cupidatat non proident sunt in culpa qui officia deserunt mollit anim id est laborum`)
+
+// generator returns a value for the row with index row.
+type generator func(r *rand.Rand, row int) interface{}
+
+// defaultGenerator returns the generator kind to use by default for column c of connType.
+func defaultGenerator(connType string, c tableColumn, primaryKey []string, foreignKey bool) string {
+	class := typeClass(connType, c)
+	switch {
+	case c.generated:
+		return "default"
+	case foreignKey:
+		return "foreign key"
+	case containsString(primaryKey, c.name) && (class == "smallint" || class == "int" || class == "bigint"):
+		return "sequence"
+	case class == "text" || class == "varchar" || class == "char":
+		return "lorem"
+	case class == "date" || class == "time" || class == "timestamp" || class == "timestamptz":
+		return "date"
+	case class == "bool":
+		return "list"
+	case class == "other" || class == "json":
+		return "default"
+	}
+	return "random"
+}
+
+// splitRange parses param "min,max", returning the defaults for empty values.
+func splitRange(param, defMin, defMax string) (min, max string, err error) {
+	if param == "" {
+		return defMin, defMax, nil
+	}
+	t := strings.Split(param, ",")
+	if len(t) != 2 {
+		return "", "", fmt.Errorf("parameters must be min,max")
+	}
+	return strings.TrimSpace(t[0]), strings.TrimSpace(t[1]), nil
+}
+
+// newGenerator returns a generator of kind for column c of connType, with param as shown in generatorHints.
+// samples are the values to pick from for foreign keys.
+func newGenerator(kind, connType string, c tableColumn, param string, samples []interface{}) (generator, error) {
+	class := typeClass(connType, c)
+	isText := class == "text" || class == "varchar" || class == "char"
+	// truncate limits generated text to the column length
+	truncate := func(s string) string {
+		if c.length.Valid && c.length.Int64 > 0 && int64(len(s)) > c.length.Int64 {
+			return strings.TrimSpace(s[:c.length.Int64])
+		}
+		return s
+	}
+
+	switch kind {
+	case "sequence":
+		start := int64(1)
+		if param != "" {
+			var err error
+			start, err = strconv.ParseInt(param, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("start must be an integer")
+			}
+		}
+		return func(r *rand.Rand, row int) interface{} {
+			v := start + int64(row)
+			if isText {
+				return truncate(strconv.FormatInt(v, 10))
+			}
+			return v
+		}, nil
+
+	case "random":
+		switch class {
+		case "smallint", "int", "bigint":
+			smin, smax, err := splitRange(param, "0", "1000")
+			if err != nil {
+				return nil, err
+			}
+			min, err1 := strconv.ParseInt(smin, 10, 64)
+			max, err2 := strconv.ParseInt(smax, 10, 64)
+			if err1 != nil || err2 != nil || max < min {
+				return nil, fmt.Errorf("bad integer range")
+			}
+			span := uint64(max-min) + 1 // 0 for the full int64 range
+			return func(r *rand.Rand, row int) interface{} {
+				v := r.Uint64()
+				if span != 0 {
+					v %= span
+				}
+				return min + int64(v)
+			}, nil
+		case "numeric", "float", "double":
+			smin, smax, err := splitRange(param, "0", "1000")
+			if err != nil {
+				return nil, err
+			}
+			min, err1 := strconv.ParseFloat(smin, 64)
+			max, err2 := strconv.ParseFloat(smax, 64)
+			if err1 != nil || err2 != nil || max < min {
+				return nil, fmt.Errorf("bad number range")
+			}
+			scale := 2
+			if c.scale.Valid {
+				scale = int(c.scale.Int64)
+			}
+			return func(r *rand.Rand, row int) interface{} {
+				v := min + r.Float64()*(max-min)
+				if class == "numeric" {
+					return strconv.FormatFloat(v, 'f', scale, 64)
+				}
+				return v
+			}, nil
+		case "bool":
+			return func(r *rand.Rand, row int) interface{} {
+				return r.Intn(2) == 1
+			}, nil
+		case "uuid":
+			return func(r *rand.Rand, row int) interface{} {
+				b := make([]byte, 16)
+				r.Read(b)
+				b[6] = b[6]&0x0f | 0x40
+				b[8] = b[8]&0x3f | 0x80
+				return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+			}, nil
+		case "binary":
+			return func(r *rand.Rand, row int) interface{} {
+				b := make([]byte, 16)
+				r.Read(b)
+				return b
+			}, nil
+		case "char", "varchar", "text":
+			const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+			n := 20
+			if c.length.Valid && c.length.Int64 > 0 && c.length.Int64 < int64(n) {
+				n = int(c.length.Int64)
+			}
+			return func(r *rand.Rand, row int) interface{} {
+				b := make([]byte, 1+r.Intn(n))
+				for i := range b {
+					b[i] = chars[r.Intn(len(chars))]
+				}
+				return string(b)
+			}, nil
+		case "date", "time", "timestamp", "timestamptz":
+			return newGenerator("date", connType, c, param, samples)
+		}
+		return nil, fmt.Errorf("no random values for type %s", c.dataType)
+
+	case "list":
+		if param == "" && class == "bool" {
+			param = "true,false"
+		}
+		var values []interface{}
+		for _, s := range strings.Split(param, ",") {
+			s = strings.TrimSpace(s)
+			if s == "" {
+				continue
+			}
+			v, err := importValue(connType, c.dataType, s)
+			if err != nil {
+				return nil, fmt.Errorf("%q: %s", s, err)
+			}
+			values = append(values, v)
+		}
+		if len(values) == 0 {
+			return nil, fmt.Errorf("list is empty")
+		}
+		return func(r *rand.Rand, row int) interface{} {
+			return values[r.Intn(len(values))]
+		}, nil
+
+	case "lorem":
+		smin, smax, err := splitRange(param, "3", "10")
+		if err != nil {
+			return nil, err
+		}
+		min, err1 := strconv.Atoi(smin)
+		max, err2 := strconv.Atoi(smax)
+		if err1 != nil || err2 != nil || min < 1 || max < min {
+			return nil, fmt.Errorf("bad number of words")
+		}
+		return func(r *rand.Rand, row int) interface{} {
+			words := make([]string, min+r.Intn(max-min+1))
+			for i := range words {
+				words[i] = loremWords[r.Intn(len(loremWords))]
+			}
+			return truncate(strings.Join(words, " "))
+		}, nil
+
+	case "date":
+		now := time.Now().Truncate(time.Second)
+		from, to := now.AddDate(-1, 0, 0), now
+		if param != "" {
+			sfrom, sto, err := splitRange(param, "", "")
+			if err != nil {
+				return nil, err
+			}
+			from, err = time.Parse("2006-01-02", sfrom)
+			if err != nil {
+				return nil, fmt.Errorf("from must be yyyy-mm-dd")
+			}
+			to, err = time.Parse("2006-01-02", sto)
+			if err != nil || to.Before(from) {
+				return nil, fmt.Errorf("to must be yyyy-mm-dd, after from")
+			}
+		}
+		span := int64(to.Sub(from)/time.Second) + 1
+		return func(r *rand.Rand, row int) interface{} {
+			t := from.Add(time.Duration(r.Int63n(span)) * time.Second)
+			switch class {
+			case "date":
+				return t.Format("2006-01-02")
+			case "time":
+				return t.Format("15:04:05")
+			case "timestamp", "timestamptz":
+				return t
+			}
+			return truncate(t.Format("2006-01-02 15:04:05"))
+		}, nil
+
+	case "foreign key":
+		if len(samples) == 0 {
+			return nil, fmt.Errorf("no values in referenced table")
+		}
+		return func(r *rand.Rand, row int) interface{} {
+			return samples[r.Intn(len(samples))]
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown generator %q", kind)
+}
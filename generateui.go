@@ -0,0 +1,354 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mjl-/duit"
+)
+
+const generateSamples = 1000 // maximum number of distinct values sampled from a referenced table
+
+// generateColumn is a column of the table with the generator chosen for it.
+type generateColumn struct {
+	column     tableColumn
+	foreignKey *foreignKey // single-column foreign key on the column, if any
+	kind       *duit.Buttongroup
+	param      *duit.Field
+	nullRatio  *duit.Field
+}
+
+// generateUI inserts rows with generated values into a table.
+type generateUI struct {
+	dbUI  *dbUI
+	table string
+	done  func() // called from main loop after rows were inserted
+
+	count     *duit.Field
+	message   *duit.Label
+	columnBox *duit.Box
+
+	columns []*generateColumn
+	loading bool               // set when columns were requested, on first layout
+	cancel  context.CancelFunc // cancels the running generation, nil if none
+
+	duit.Box
+}
+
+func newGenerateUI(dbUI *dbUI, table string, done func()) (ui *generateUI) {
+	ui = &generateUI{dbUI: dbUI, table: table, done: done}
+	ui.count = &duit.Field{Text: "100"}
+	ui.message = &duit.Label{}
+	ui.columnBox = &duit.Box{}
+	generate := &duit.Button{
+		Text:     "generate",
+		Colorset: &dui.Primary,
+		Click: func() (e duit.Event) {
+			ui.generate()
+			return
+		},
+	}
+	cancel := &duit.Button{
+		Text: "cancel",
+		Click: func() (e duit.Event) {
+			if ui.cancel != nil {
+				ui.cancel()
+			}
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(
+		&duit.Box{
+			Padding: duit.SpaceXY(4, 2),
+			Margin:  image.Pt(4, 2),
+			Valign:  duit.ValignMiddle,
+			Kids: duit.NewKids(
+				label("insert"),
+				&duit.Box{Width: 80, Kids: duit.NewKids(ui.count)},
+				label("rows"),
+				generate,
+				cancel,
+				ui.message,
+			),
+		},
+		duit.NewScroll(&duit.Box{Padding: duit.SpaceXY(4, 2), Kids: duit.NewKids(ui.columnBox)}),
+	)
+	return
+}
+
+func (ui *generateUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+// Layout reads the columns when the tab is first shown.
+func (ui *generateUI) Layout(dui *duit.DUI, self *duit.Kid, sizeAvail image.Point, force bool) {
+	if !ui.loading {
+		ui.loading = true
+		ui.init()
+	}
+	ui.Box.Layout(dui, self, sizeAvail, force)
+}
+
+// init reads the columns and foreign keys of the table, and shows a generator for each column.
+// Called from main loop.
+func (ui *generateUI) init() {
+	ui.message.Text = "reading columns..."
+	connType := ui.dbUI.connUI.config.Type
+	go func() {
+		lcheck, handle := errorHandler(func(err error) {
+			dui.Call <- func() {
+				ui.message.Text = fmt.Sprintf("error: %s", err)
+				ui.layout()
+			}
+		})
+		defer handle()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		columns, primaryKey, err := loadTableColumns(ctx, ui.dbUI.db, connType, ui.dbUI.dbName, ui.table)
+		lcheck(err, "listing columns")
+		fks, err := listForeignKeys(ctx, ui.dbUI.db, connType, ui.dbUI.dbName)
+		lcheck(err, "listing foreign keys")
+
+		// sequences continue after the existing rows, so the primary key stays unique
+		kinds := make([]string, len(columns))
+		fkeys := make([]*foreignKey, len(columns))
+		starts := map[string]int64{}
+		for i, c := range columns {
+			for j, fk := range fks {
+				if fk.table == ui.table && len(fk.columns) == 1 && fk.columns[0] == c.name {
+					fkeys[i] = &fks[j]
+				}
+			}
+			kinds[i] = defaultGenerator(connType, c, primaryKey, fkeys[i] != nil)
+			if kinds[i] == "sequence" {
+				starts[c.name], err = ui.maxValue(ctx, c.name)
+				lcheck(err, "reading maximum of "+c.name)
+				starts[c.name]++
+			}
+		}
+
+		dui.Call <- func() {
+			defer ui.layout()
+			ui.message.Text = ""
+			ui.columns = nil
+			uis := []duit.UI{
+				&duit.Label{Font: bold, Text: "column"},
+				&duit.Label{Font: bold, Text: "type"},
+				&duit.Label{Font: bold, Text: "generator"},
+				&duit.Label{Font: bold, Text: "parameters"},
+				&duit.Label{Font: bold, Text: "NULL ratio"},
+			}
+			for i, c := range columns {
+				gc := &generateColumn{column: c, foreignKey: fkeys[i]}
+				kind := kinds[i]
+				gc.param = &duit.Field{Placeholder: generatorHints[kind]}
+				if start, ok := starts[c.name]; ok {
+					gc.param.Text = fmt.Sprintf("%d", start)
+				}
+				gc.nullRatio = &duit.Field{Text: "0", Disabled: !c.nullable}
+				gc.kind = &duit.Buttongroup{
+					Texts:    generatorKinds,
+					Selected: indexOf(generatorKinds, kind),
+					Changed: func(index int) (e duit.Event) {
+						gc.param.Placeholder = generatorHints[generatorKinds[index]]
+						ui.layout()
+						return
+					},
+				}
+				typ := c.dataType
+				if gc.foreignKey != nil {
+					typ += fmt.Sprintf(", references %s(%s)", gc.foreignKey.refTable, gc.foreignKey.refColumns[0])
+				}
+				ui.columns = append(ui.columns, gc)
+				uis = append(uis, label(c.name), label(typ), gc.kind, &duit.Box{Width: 200, Kids: duit.NewKids(gc.param)}, &duit.Box{Width: 50, Kids: duit.NewKids(gc.nullRatio)})
+			}
+			padding := duit.Space{Top: 1, Right: 4, Bottom: 1, Left: 4}
+			valign := duit.ValignMiddle
+			ui.columnBox.Kids = duit.NewKids(&duit.Grid{
+				Columns: 5,
+				Padding: []duit.Space{padding, padding, padding, padding, padding},
+				Valign:  []duit.Valign{valign, valign, valign, valign, valign},
+				Kids:    duit.NewKids(uis...),
+			})
+		}
+	}()
+}
+
+// generateSpec is a column with its generator settings, read in the main loop.
+type generateSpec struct {
+	column     tableColumn
+	foreignKey *foreignKey
+	kind       string
+	param      string
+	nullRatio  float64
+}
+
+// generate starts inserting rows with generated values.
+// Called from main loop.
+func (ui *generateUI) generate() {
+	defer ui.layout()
+	if ui.cancel != nil {
+		ui.message.Text = "already generating"
+		return
+	}
+	count, err := strconv.Atoi(ui.count.Text)
+	if err != nil || count <= 0 {
+		ui.message.Text = "error: bad number of rows"
+		return
+	}
+	var specs []generateSpec
+	for _, gc := range ui.columns {
+		s := generateSpec{
+			column:     gc.column,
+			foreignKey: gc.foreignKey,
+			kind:       generatorKinds[gc.kind.Selected],
+			param:      gc.param.Text,
+		}
+		if s.kind == "default" {
+			continue
+		}
+		if s.kind == "foreign key" && s.foreignKey == nil {
+			ui.message.Text = fmt.Sprintf("error: column %s has no foreign key", s.column.name)
+			return
+		}
+		if s.column.nullable {
+			s.nullRatio, err = strconv.ParseFloat(gc.nullRatio.Text, 64)
+			if err != nil || s.nullRatio < 0 || s.nullRatio > 1 {
+				ui.message.Text = fmt.Sprintf("error: NULL ratio of column %s must be between 0 and 1", s.column.name)
+				return
+			}
+		}
+		specs = append(specs, s)
+	}
+	if len(specs) == 0 {
+		ui.message.Text = "error: no columns to generate"
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancel = cancel
+	ui.message.Text = "generating..."
+	go ui._generate(ctx, cancel, count, specs)
+}
+
+// Called from outside main loop.
+func (ui *generateUI) _generate(ctx context.Context, cancel context.CancelFunc, count int, specs []generateSpec) {
+	defer cancel()
+	lcheck, handle := errorHandler(func(err error) {
+		dui.Call <- func() {
+			ui.cancel = nil
+			if ctx.Err() == context.Canceled {
+				ui.message.Text = "canceled, no rows inserted"
+			} else {
+				ui.message.Text = fmt.Sprintf("error: %s", err)
+			}
+			ui.layout()
+		}
+	})
+	defer handle()
+
+	connType := ui.dbUI.connUI.config.Type
+	generators := make([]generator, len(specs))
+	names := make([]string, len(specs))
+	for i, s := range specs {
+		var samples []interface{}
+		if s.kind == "foreign key" {
+			var err error
+			samples, err = ui.sampleValues(ctx, s.foreignKey.refTable, s.foreignKey.refColumns[0])
+			lcheck(err, "reading values of "+s.foreignKey.refTable)
+		}
+		g, err := newGenerator(s.kind, connType, s.column, s.param, samples)
+		lcheck(err, "column "+s.column.name)
+		generators[i] = g
+		names[i] = s.column.name
+	}
+
+	tx, err := ui.dbUI.db.BeginTx(ctx, nil)
+	lcheck(err, "starting transaction")
+	defer tx.Rollback()
+
+	batch := maxParams(connType) / len(specs)
+	if batch > importBatch {
+		batch = importBatch
+	}
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var args []interface{}
+	for row := 0; row < count; row++ {
+		for i, s := range specs {
+			if s.nullRatio > 0 && r.Float64() < s.nullRatio {
+				args = append(args, nil)
+			} else {
+				args = append(args, generators[i](r, row))
+			}
+		}
+		n := len(args) / len(specs)
+		if n < batch && row < count-1 {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, insertSQL(connType, ui.table, names, n), args...)
+		lcheck(err, "inserting rows")
+		args = args[:0]
+		msg := fmt.Sprintf("inserted %d of %d rows...", row+1, count)
+		dui.Call <- func() {
+			ui.message.Text = msg
+			ui.layout()
+		}
+	}
+	err = tx.Commit()
+	lcheck(err, "committing")
+
+	dui.Call <- func() {
+		ui.cancel = nil
+		ui.message.Text = fmt.Sprintf("inserted %d rows", count)
+		ui.layout()
+		ui.done()
+	}
+}
+
+// maxValue returns the largest value of integer column in the table, 0 if the table is empty.
+// Called from outside main loop.
+func (ui *generateUI) maxValue(ctx context.Context, column string) (int64, error) {
+	connType := ui.dbUI.connUI.config.Type
+	q := fmt.Sprintf("select coalesce(max(%s), 0) from %s", quoteIdent(connType, column), quoteIdent(connType, ui.table))
+	var max int64
+	err := ui.dbUI.db.QueryRowContext(ctx, q).Scan(&max)
+	return max, err
+}
+
+// sampleValues returns distinct values of column in table, to pick foreign key values from.
+// Called from outside main loop.
+func (ui *generateUI) sampleValues(ctx context.Context, table, column string) ([]interface{}, error) {
+	connType := ui.dbUI.connUI.config.Type
+	col := quoteIdent(connType, column)
+	q := fmt.Sprintf("select distinct %s from %s where %s is not null", col, quoteIdent(connType, table), col)
+	if connType == "sqlserver" {
+		q = fmt.Sprintf("select distinct top %d %s", generateSamples, strings.TrimPrefix(q, "select distinct "))
+	} else {
+		q += fmt.Sprintf(" limit %d", generateSamples)
+	}
+	rows, err := ui.dbUI.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	kind := columnKind(connType, colTypes[0].DatabaseTypeName())
+	var values []interface{}
+	for rows.Next() {
+		var v interface{}
+		err = rows.Scan(&v)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err)
+		}
+		values = append(values, copyValue(kind, "", v))
+	}
+	return values, rows.Err()
+}
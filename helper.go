@@ -1,9 +1,94 @@
 package main
 
 import (
+	"bytes"
+	"image"
+	"strings"
+
+	"9fans.net/go/draw"
 	"github.com/mjl-/duit"
 )
 
 func label(s string) *duit.Label {
 	return &duit.Label{Text: s}
 }
+
+// toolbar returns a box with kids next to each other, for buttons and fields above a view.
+func toolbar(kids ...duit.UI) *duit.Box {
+	return &duit.Box{
+		Padding: duit.SpaceXY(4, 2),
+		Margin:  image.Pt(4, 2),
+		Valign:  duit.ValignMiddle,
+		Kids:    duit.NewKids(kids...),
+	}
+}
+
+// readOnlyEdit returns an Edit showing text, that can be scrolled, searched and copied from, but not changed.
+func readOnlyEdit(text string) *duit.Edit {
+	edit, _ := duit.NewEdit(bytes.NewReader([]byte(text)))
+	edit.Keys = func(k rune, m draw.Mouse) (e duit.Event) {
+		switch k {
+		case draw.KeyPageUp, draw.KeyPageDown, draw.KeyUp, draw.KeyDown, draw.KeyLeft, draw.KeyRight:
+			return
+		}
+		// other command keys are handled by the Edit or passed on, except those changing the text
+		if k >= draw.KeyCmd && k < draw.KeyCmd+128 && !strings.ContainsRune("xvzZ[]y", k-draw.KeyCmd) {
+			return
+		}
+		e.Consumed = true
+		return
+	}
+	return edit
+}
+
+// oneLine returns s with newlines and tabs replaced by spaces, for showing in a gridlist.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// containsString returns whether l contains s.
+func containsString(l []string, s string) bool {
+	for _, e := range l {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// indexOf returns the index of s in l, or -1.
+func indexOf(l []string, s string) int {
+	for i, e := range l {
+		if e == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// likeMatch returns whether s matches the SQL like-pattern, with % matching any text and _ any character, ignoring case.
+func likeMatch(pattern, s string) bool {
+	p, t := []rune(strings.ToLower(pattern)), []rune(strings.ToLower(s))
+	// star is the position in p after the last %, and in t where it started matching
+	star, mark := -1, 0
+	i, j := 0, 0
+	for j < len(t) {
+		switch {
+		case i < len(p) && (p[i] == '_' || p[i] == t[j]):
+			i++
+			j++
+		case i < len(p) && p[i] == '%':
+			star, mark = i+1, j
+			i++
+		case star >= 0:
+			mark++
+			i, j = star, mark
+		default:
+			return false
+		}
+	}
+	for i < len(p) && p[i] == '%' {
+		i++
+	}
+	return i == len(p)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// importOptions describe how to read a file for importing.
+type importOptions struct {
+	format    string // csv, tsv or jsonl
+	delimiter string // for csv
+	quote     string // for csv, `"` or empty for no quoting
+	header    bool   // for csv/tsv, whether the first line holds column names
+	null      string // for csv/tsv, values equal to this are NULL. if empty, empty values are NULL
+}
+
+// importReader reads records from a CSV, TSV or JSON-lines file.
+// Each record has a value per column, nil for NULL.
+type importReader struct {
+	columns []string
+	next    func() ([]*string, error) // returns io.EOF at end of file
+	file    *os.File
+}
+
+func (r *importReader) Close() error {
+	return r.file.Close()
+}
+
+// openImport opens the file at path and determines its columns, from the header line, the number of values
+// in the first line, or the keys of the first JSON object.
+func openImport(path string, opts importOptions) (r *importReader, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+		}
+	}()
+	r = &importReader{file: f}
+	br := bufio.NewReader(f)
+
+	if opts.format == "jsonl" {
+		var pending map[string]*string
+		readObject := func() (keys []string, values map[string]*string, err error) {
+			for {
+				line, err := br.ReadBytes('\n')
+				if err != nil && (err != io.EOF || len(line) == 0) {
+					return nil, nil, err
+				}
+				line = bytes.TrimSpace(line)
+				if len(line) > 0 {
+					return parseJSONObject(line)
+				}
+			}
+		}
+		r.columns, pending, err = readObject()
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("reading first object: %s", err)
+		}
+		r.next = func() ([]*string, error) {
+			values := pending
+			pending = nil
+			if values == nil {
+				var err error
+				_, values, err = readObject()
+				if err != nil {
+					return nil, err
+				}
+			}
+			l := make([]*string, len(r.columns))
+			for i, k := range r.columns {
+				l[i] = values[k]
+			}
+			return l, nil
+		}
+		return r, nil
+	}
+
+	delimiter := opts.delimiter
+	if opts.format == "tsv" {
+		delimiter = "\t"
+	}
+	if len(delimiter) != 1 {
+		return nil, fmt.Errorf("delimiter must be a single character")
+	}
+	var read func() ([]string, error)
+	if opts.quote == `"` {
+		cr := csv.NewReader(br)
+		cr.Comma = rune(delimiter[0])
+		cr.FieldsPerRecord = -1
+		cr.LazyQuotes = true
+		read = cr.Read
+	} else if opts.quote == "" {
+		read = func() ([]string, error) {
+			for {
+				line, err := br.ReadString('\n')
+				if err != nil && (err != io.EOF || line == "") {
+					return nil, err
+				}
+				line = strings.TrimRight(line, "\r\n")
+				if line != "" {
+					return strings.Split(line, delimiter), nil
+				}
+			}
+		}
+	} else {
+		return nil, fmt.Errorf(`quote must be " or empty`)
+	}
+
+	first, err := read()
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("reading first line: %s", err)
+	}
+	if opts.header {
+		r.columns = first
+		first = nil
+	} else {
+		for i := range first {
+			r.columns = append(r.columns, fmt.Sprintf("%d", i+1))
+		}
+	}
+	r.next = func() ([]*string, error) {
+		record := first
+		first = nil
+		if record == nil {
+			var err error
+			record, err = read()
+			if err != nil {
+				return nil, err
+			}
+		}
+		l := make([]*string, len(r.columns))
+		for i := range l {
+			if i >= len(record) {
+				continue
+			}
+			s := record[i]
+			if s != opts.null && !(opts.null == "" && s == "") {
+				l[i] = &s
+			}
+		}
+		return l, nil
+	}
+	return r, nil
+}
+
+// parseJSONObject returns the keys in order of appearance, and the values as text.
+// Strings are unquoted, other values are kept as JSON, null is nil.
+func parseJSONObject(buf []byte) (keys []string, values map[string]*string, err error) {
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	t, err := dec.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+	if d, ok := t.(json.Delim); !ok || d != '{' {
+		return nil, nil, fmt.Errorf("line is not a JSON object")
+	}
+	values = map[string]*string{}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, nil, err
+		}
+		k, _ := t.(string)
+		var raw json.RawMessage
+		err = dec.Decode(&raw)
+		if err != nil {
+			return nil, nil, err
+		}
+		if _, ok := values[k]; !ok {
+			keys = append(keys, k)
+		}
+		var s string
+		switch {
+		case string(raw) == "null":
+			values[k] = nil
+			continue
+		case len(raw) > 0 && raw[0] == '"':
+			err = json.Unmarshal(raw, &s)
+			if err != nil {
+				return nil, nil, err
+			}
+		default:
+			s = string(raw)
+		}
+		values[k] = &s
+	}
+	return keys, values, nil
+}
+
+var importTimeLayouts = []string{
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05-07",
+	"2006-01-02 15:04:05.999999999-07",
+	time.RFC3339Nano,
+	"15:04:05",
+	"15:04:05.999999999",
+}
+
+// importTimeTypes are the date and time types, values are checked against importTimeLayouts.
+var importTimeTypes = map[string]bool{
+	"date":                        true,
+	"time":                        true,
+	"time without time zone":      true,
+	"time with time zone":         true,
+	"timestamp":                   true,
+	"timestamp without time zone": true,
+	"timestamp with time zone":    true,
+	"datetime":                    true,
+	"datetime2":                   true,
+	"smalldatetime":               true,
+	"datetimeoffset":              true,
+}
+
+// parseBool parses s as boolean, accepting true/false, t/f, yes/no, y/n and 1/0.
+func parseBool(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "true", "t", "yes", "y", "1":
+		return true, nil
+	case "false", "f", "no", "n", "0":
+		return false, nil
+	}
+	return false, fmt.Errorf("not a boolean")
+}
+
+// importValue checks that s is valid for a column of dataType (as in information_schema, lower case) of connType,
+// and returns the value to pass to the database driver.
+func importValue(connType, dataType, s string) (interface{}, error) {
+	switch {
+	case dataType == "bit" && connType == "postgres":
+		// bit string
+		return s, nil
+	case dataType == "bit" && connType == "mysql":
+		// bit(n), with bit(1) often used as boolean
+		if b, err := parseBool(s); err == nil {
+			if b {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		}
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("not a boolean or integer")
+		}
+		return v, nil
+	case intType(dataType):
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("not an integer")
+		}
+		return v, nil
+	case dataType == "numeric" || dataType == "decimal" || dataType == "money" || dataType == "smallmoney":
+		if _, err := strconv.ParseFloat(s, 64); err != nil {
+			return nil, fmt.Errorf("not a number")
+		}
+		return s, nil
+	case dataType == "real" || dataType == "double precision" || dataType == "double" || dataType == "float":
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("not a number")
+		}
+		return v, nil
+	case dataType == "boolean" || dataType == "bool" || dataType == "bit":
+		return parseBool(s)
+	case dataType == "uuid" || dataType == "uniqueidentifier":
+		if !guidRegexp.MatchString(s) {
+			return nil, fmt.Errorf("not a uuid")
+		}
+		return s, nil
+	case importTimeTypes[dataType]:
+		for _, layout := range importTimeLayouts {
+			if _, err := time.Parse(layout, s); err == nil {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("not a date/time")
+	}
+	return s, nil
+}
@@ -0,0 +1,546 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	"github.com/lib/pq"
+	"github.com/mjl-/duit"
+)
+
+const (
+	importBatch     = 1000 // rows per progress update, and per insert statement for mysql
+	importPreview   = 10   // rows shown in preview
+	importMaxErrors = 100  // invalid rows listed
+)
+
+var importFormats = []string{"csv", "tsv", "jsonl"}
+
+// importColumn is a column of the table to import into, with the file column to read its value from.
+type importColumn struct {
+	name     string
+	dataType string // from information_schema, lower case
+	from     *duit.Field
+	sample   *duit.Label
+}
+
+// importError is a problem with a row in the file.
+type importError struct {
+	row    int // 1 for the first row after the header
+	column string
+	err    error
+}
+
+// importUI reads a CSV, TSV or JSON-lines file into a table.
+type importUI struct {
+	dbUI  *dbUI
+	table string
+	done  func() // called from main loop after a successful import
+
+	path        *duit.Field
+	format      *duit.Buttongroup
+	delimiter   *duit.Field
+	quote       *duit.Field
+	header      *duit.Checkbox
+	null        *duit.Field
+	skipInvalid *duit.Checkbox
+	message     *duit.Label
+	mappingBox  *duit.Box
+	previewBox  *duit.Box
+	errors      *duit.Gridlist
+
+	columns     []importColumn
+	fileColumns []string
+	cancel      context.CancelFunc // cancels the running import, nil if none
+
+	duit.Box
+}
+
+func newImportUI(dbUI *dbUI, table string, done func()) (ui *importUI) {
+	ui = &importUI{dbUI: dbUI, table: table, done: done}
+	ui.path = &duit.Field{Placeholder: "path of file..."}
+	ui.format = &duit.Buttongroup{Texts: importFormats}
+	ui.delimiter = &duit.Field{Text: ","}
+	ui.quote = &duit.Field{Text: `"`}
+	ui.header = &duit.Checkbox{Checked: true}
+	ui.null = &duit.Field{Placeholder: "empty"}
+	ui.skipInvalid = &duit.Checkbox{}
+	ui.message = &duit.Label{}
+	ui.mappingBox = &duit.Box{}
+	ui.previewBox = &duit.Box{}
+	ui.errors = &duit.Gridlist{
+		Header:  &duit.Gridrow{Values: []string{"row", "column", "error"}},
+		Halign:  []duit.Halign{duit.HalignRight, duit.HalignLeft, duit.HalignLeft},
+		Striped: true,
+		Padding: duit.SpaceXY(4, 2),
+	}
+
+	preview := &duit.Button{
+		Text: "preview",
+		Click: func() (e duit.Event) {
+			ui.preview()
+			return
+		},
+	}
+	importButton := &duit.Button{
+		Text:     "import",
+		Colorset: &dui.Primary,
+		Click: func() (e duit.Event) {
+			ui.startImport()
+			return
+		},
+	}
+	cancel := &duit.Button{
+		Text: "cancel",
+		Click: func() (e duit.Event) {
+			if ui.cancel != nil {
+				ui.cancel()
+			}
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(
+		toolbar(
+			&duit.Box{Width: 300, Kids: duit.NewKids(ui.path)},
+			ui.format,
+			label("delimiter"),
+			&duit.Box{Width: 30, Kids: duit.NewKids(ui.delimiter)},
+			label("quote"),
+			&duit.Box{Width: 30, Kids: duit.NewKids(ui.quote)},
+			ui.header,
+			label("header line"),
+			label("NULL"),
+			&duit.Box{Width: 60, Kids: duit.NewKids(ui.null)},
+			preview,
+		),
+		toolbar(
+			ui.skipInvalid,
+			label("skip invalid rows"),
+			importButton,
+			cancel,
+			ui.message,
+		),
+		duit.NewScroll(
+			&duit.Box{
+				Padding: duit.SpaceXY(4, 2),
+				Kids: duit.NewKids(
+					&duit.Label{Font: bold, Text: "columns"},
+					ui.mappingBox,
+					&duit.Label{Font: bold, Text: "preview"},
+					ui.previewBox,
+					&duit.Label{Font: bold, Text: "invalid rows"},
+					ui.errors,
+				),
+			},
+		),
+	)
+	return
+}
+
+func (ui *importUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+// options returns the file options as currently set.
+// Called from main loop.
+func (ui *importUI) options() importOptions {
+	return importOptions{
+		format:    importFormats[ui.format.Selected],
+		delimiter: ui.delimiter.Text,
+		quote:     ui.quote.Text,
+		header:    ui.header.Checked,
+		null:      ui.null.Text,
+	}
+}
+
+// preview reads the columns of the table and the first rows of the file, and shows how they map.
+// Called from main loop.
+func (ui *importUI) preview() {
+	defer ui.layout()
+	if ui.cancel != nil {
+		ui.message.Text = "preview or import already running"
+		return
+	}
+	ui.message.Text = "reading..."
+	path := ui.path.Text
+	opts := ui.options()
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	ui.cancel = cancel
+	go func() {
+		defer cancel()
+		lcheck, handle := errorHandler(func(err error) {
+			dui.Call <- func() {
+				ui.cancel = nil
+				ui.message.Text = fmt.Sprintf("error: %s", err)
+				ui.layout()
+			}
+		})
+		defer handle()
+
+		columns, err := ui.tableColumns(ctx)
+		lcheck(err, "listing columns")
+
+		r, err := openImport(path, opts)
+		lcheck(err, "opening file")
+		defer r.Close()
+		var records [][]*string
+		for len(records) < importPreview {
+			record, err := r.next()
+			if err == io.EOF {
+				break
+			}
+			lcheck(err, "reading file")
+			records = append(records, record)
+		}
+
+		dui.Call <- func() {
+			defer ui.layout()
+			ui.cancel = nil
+			ui.fileColumns = r.columns
+			ui.columns = columns
+			mappingUIs := []duit.UI{
+				&duit.Label{Font: bold, Text: "column"},
+				&duit.Label{Font: bold, Text: "type"},
+				&duit.Label{Font: bold, Text: "from file column"},
+				&duit.Label{Font: bold, Text: "first value"},
+			}
+			for i := range ui.columns {
+				c := &ui.columns[i]
+				c.from = &duit.Field{Placeholder: "skip"}
+				c.sample = &duit.Label{}
+				for j, fc := range r.columns {
+					if strings.EqualFold(fc, c.name) || (!opts.header && j == i) {
+						c.from.Text = fc
+					}
+				}
+				c.from.Changed = func(string) (e duit.Event) {
+					ui.showSample(c, records)
+					return
+				}
+				ui.showSample(c, records)
+				mappingUIs = append(mappingUIs, label(c.name), label(c.dataType), &duit.Box{Width: 150, Kids: duit.NewKids(c.from)}, c.sample)
+			}
+			ui.mappingBox.Kids = duit.NewKids(&duit.Grid{
+				Columns: 4,
+				Padding: []duit.Space{
+					duit.Space{Top: 1, Right: 4, Bottom: 1, Left: 0},
+					duit.Space{Top: 1, Right: 4, Bottom: 1, Left: 4},
+					duit.Space{Top: 1, Right: 4, Bottom: 1, Left: 4},
+					duit.Space{Top: 1, Right: 0, Bottom: 1, Left: 4},
+				},
+				Valign: []duit.Valign{duit.ValignMiddle, duit.ValignMiddle, duit.ValignMiddle, duit.ValignMiddle},
+				Kids:   duit.NewKids(mappingUIs...),
+			})
+
+			var rows []*duit.Gridrow
+			for _, record := range records {
+				values := make([]string, len(record))
+				for i, v := range record {
+					if v == nil {
+						values[i] = "NULL"
+					} else {
+						values[i] = oneLine(*v)
+					}
+				}
+				rows = append(rows, &duit.Gridrow{Values: values})
+			}
+			if len(r.columns) == 0 {
+				ui.previewBox.Kids = duit.NewKids(label("no columns in file"))
+			} else {
+				ui.previewBox.Kids = duit.NewKids(&duit.Gridlist{
+					Header:  &duit.Gridrow{Values: r.columns},
+					Rows:    rows,
+					Striped: true,
+					Padding: duit.SpaceXY(4, 2),
+				})
+			}
+			ui.message.Text = fmt.Sprintf("%d columns in file", len(r.columns))
+		}
+	}()
+}
+
+// showSample shows the value of the first record for the column, or why it is not valid.
+// Called from main loop.
+func (ui *importUI) showSample(c *importColumn, records [][]*string) {
+	defer ui.layout()
+	index := indexOf(ui.fileColumns, c.from.Text)
+	switch {
+	case c.from.Text == "":
+		c.sample.Text = "(default)"
+	case index < 0:
+		c.sample.Text = "no such file column"
+	case len(records) == 0:
+		c.sample.Text = ""
+	case records[0][index] == nil:
+		c.sample.Text = "NULL"
+	default:
+		s := *records[0][index]
+		if _, err := importValue(ui.dbUI.connUI.config.Type, c.dataType, s); err != nil {
+			c.sample.Text = fmt.Sprintf("%s: %s", oneLine(s), err)
+		} else {
+			c.sample.Text = oneLine(s)
+		}
+	}
+}
+
+// tableColumns returns the columns of the table.
+// Called from outside main loop.
+func (ui *importUI) tableColumns(ctx context.Context) ([]importColumn, error) {
+	var q string
+	var args []interface{}
+	switch ui.dbUI.connUI.config.Type {
+	case "postgres":
+		q = `
+			select column_name, data_type
+			from information_schema.columns
+			where table_schema || '.' || table_name=$1
+			order by ordinal_position
+		`
+		args = append(args, ui.table)
+	case "mysql":
+		q = `
+			select column_name, data_type
+			from information_schema.columns
+			where table_schema=? and table_name=?
+			order by ordinal_position
+		`
+		args = append(args, ui.dbUI.dbName, ui.table)
+	case "sqlserver":
+		q = `
+			select column_name, data_type
+			from information_schema.columns
+			where concat(table_schema, '.', table_name)=@name
+			order by ordinal_position
+		`
+		args = append(args, sql.Named("name", ui.table))
+	default:
+		panic("bad connection type")
+	}
+	rows, err := ui.dbUI.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var columns []importColumn
+	for rows.Next() {
+		var c importColumn
+		err = rows.Scan(&c.name, &c.dataType)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err)
+		}
+		c.dataType = strings.ToLower(c.dataType)
+		columns = append(columns, c)
+	}
+	return columns, rows.Err()
+}
+
+// startImport imports the file with the current column mapping.
+// Called from main loop.
+func (ui *importUI) startImport() {
+	defer ui.layout()
+	if ui.cancel != nil {
+		ui.message.Text = "import already running"
+		return
+	}
+	if ui.columns == nil {
+		ui.message.Text = "preview the file first"
+		return
+	}
+	var columns []importColumn
+	var fileColumns []string
+	for _, c := range ui.columns {
+		if c.from.Text == "" {
+			continue
+		}
+		if indexOf(ui.fileColumns, c.from.Text) < 0 {
+			ui.message.Text = fmt.Sprintf("column %s: no file column %q", c.name, c.from.Text)
+			return
+		}
+		columns = append(columns, c)
+		fileColumns = append(fileColumns, c.from.Text)
+	}
+	if len(columns) == 0 {
+		ui.message.Text = "no columns to import"
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancel = cancel
+	ui.errors.Rows = nil
+	ui.message.Text = "importing..."
+	go ui._import(ctx, cancel, ui.path.Text, ui.options(), columns, fileColumns, ui.skipInvalid.Checked)
+}
+
+// called from outside main loop
+func (ui *importUI) _import(ctx context.Context, cancel context.CancelFunc, path string, opts importOptions, columns []importColumn, fileColumns []string, skipInvalid bool) {
+	var invalid []importError
+	var nInvalid int
+	showErrors := func() {
+		var rows []*duit.Gridrow
+		for _, e := range invalid {
+			rows = append(rows, &duit.Gridrow{Values: []string{fmt.Sprintf("%d", e.row), e.column, e.err.Error()}})
+		}
+		ui.errors.Rows = rows
+	}
+
+	lcheck, handle := errorHandler(func(err error) {
+		dui.Call <- func() {
+			ui.cancel = nil
+			showErrors()
+			ui.message.Text = fmt.Sprintf("error: %s; nothing imported", err)
+			ui.layout()
+		}
+	})
+	defer handle()
+	defer cancel()
+
+	r, err := openImport(path, opts)
+	lcheck(err, "opening file")
+	defer r.Close()
+	indices := make([]int, len(fileColumns))
+	names := make([]string, len(columns))
+	for i, fc := range fileColumns {
+		indices[i] = indexOf(r.columns, fc)
+		if indices[i] < 0 {
+			lcheck(fmt.Errorf("no column %q in file", fc), "mapping columns")
+		}
+		names[i] = columns[i].name
+	}
+
+	tx, err := ui.dbUI.db.BeginTx(ctx, nil)
+	lcheck(err, "starting transaction")
+	committed := false
+	defer func() {
+		if !committed {
+			tx.Rollback()
+		}
+	}()
+
+	// add queues a row for inserting, flush makes sure all rows are sent
+	var add func(values []interface{}) error
+	var flush func() error
+	connType := ui.dbUI.connUI.config.Type
+	switch connType {
+	case "postgres", "sqlserver":
+		var q string
+		if connType == "postgres" {
+			t := strings.SplitN(ui.table, ".", 2)
+			if len(t) == 2 {
+				q = pq.CopyInSchema(t[0], t[1], names...)
+			} else {
+				q = pq.CopyIn(ui.table, names...)
+			}
+		} else {
+			q = mssql.CopyIn(quoteIdent(connType, ui.table), mssql.MssqlBulkOptions{}, names...)
+		}
+		stmt, err := tx.PrepareContext(ctx, q)
+		lcheck(err, "starting copy")
+		defer stmt.Close()
+		add = func(values []interface{}) error {
+			_, err := stmt.ExecContext(ctx, values...)
+			return err
+		}
+		flush = func() error {
+			_, err := stmt.ExecContext(ctx)
+			return err
+		}
+	case "mysql":
+		quoted := make([]string, len(names))
+		for i, name := range names {
+			quoted[i] = quoteIdent(connType, name)
+		}
+		batch := importBatch
+		if max := 60000 / len(names); batch > max {
+			// stay below the limit of 65535 placeholders
+			batch = max
+		}
+		placeholders := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(names)), ", ") + ")"
+		var pending []interface{}
+		var n int
+		flush = func() error {
+			if n == 0 {
+				return nil
+			}
+			q := fmt.Sprintf("insert into %s (%s) values %s", quoteIdent(connType, ui.table), strings.Join(quoted, ", "), strings.TrimSuffix(strings.Repeat(placeholders+", ", n), ", "))
+			_, err := tx.ExecContext(ctx, q, pending...)
+			pending = nil
+			n = 0
+			return err
+		}
+		add = func(values []interface{}) error {
+			pending = append(pending, values...)
+			n++
+			if n >= batch {
+				return flush()
+			}
+			return nil
+		}
+	default:
+		panic("bad connection type")
+	}
+
+	var row, imported int
+	for {
+		record, err := r.next()
+		if err == io.EOF {
+			break
+		}
+		row++
+		lcheck(err, fmt.Sprintf("reading row %d", row))
+
+		values := make([]interface{}, len(columns))
+		valid := true
+		for i, c := range columns {
+			v := record[indices[i]]
+			if v == nil {
+				continue
+			}
+			values[i], err = importValue(connType, c.dataType, *v)
+			if err != nil {
+				valid = false
+				if len(invalid) < importMaxErrors {
+					invalid = append(invalid, importError{row, c.name, fmt.Errorf("%q: %s", oneLine(*v), err)})
+				}
+			}
+		}
+		if !valid {
+			nInvalid++
+			if !skipInvalid {
+				lcheck(fmt.Errorf("invalid value"), fmt.Sprintf("row %d", row))
+			}
+			continue
+		}
+		err = add(values)
+		lcheck(err, fmt.Sprintf("inserting row %d", row))
+		imported++
+		if imported%importBatch == 0 {
+			n := imported
+			dui.Call <- func() {
+				ui.message.Text = fmt.Sprintf("importing... %d rows", n)
+				ui.layout()
+			}
+		}
+	}
+	err = flush()
+	lcheck(err, "inserting rows")
+	err = tx.Commit()
+	lcheck(err, "committing")
+	committed = true
+
+	dui.Call <- func() {
+		ui.cancel = nil
+		showErrors()
+		msg := fmt.Sprintf("imported %d rows", imported)
+		if nInvalid > 0 {
+			msg += fmt.Sprintf(", skipped %d invalid rows", nInvalid)
+		}
+		ui.message.Text = msg
+		ui.layout()
+		ui.done()
+	}
+}
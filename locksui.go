@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"image"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mjl-/duit"
+)
+
+// lockSession is a session holding or waiting for locks, as shown in the locks UI.
+type lockSession struct {
+	id       string
+	blockers []string // sessions this session waits for
+	locks    []string // "type mode object"
+	waiting  bool     // whether any lock is not granted
+	query    string
+}
+
+// locksUI shows the sessions holding or waiting for locks, as tree of blocking chains.
+type locksUI struct {
+	connUI  *connUI
+	message *duit.Label
+	grid    *duit.Gridlist
+	gridBox *duit.Box
+
+	duit.Box
+}
+
+func newLocksUI(cUI *connUI) (ui *locksUI) {
+	ui = &locksUI{connUI: cUI}
+	ui.message = &duit.Label{}
+	ui.gridBox = &duit.Box{}
+	ui.grid = &duit.Gridlist{
+		Header:  &duit.Gridrow{Values: []string{"session", "blocked by", "status", "locks", "query"}},
+		Striped: true,
+		Padding: duit.SpaceXY(4, 2),
+	}
+	refresh := &duit.Button{
+		Text: "refresh",
+		Click: func() (e duit.Event) {
+			ui.load()
+			return
+		},
+	}
+	jump := &duit.Button{
+		Text: "go to blocking session",
+		Click: func() (e duit.Event) {
+			sel := ui.grid.Selected()
+			if len(sel) != 1 {
+				ui.message.Text = "select a session first"
+				dui.MarkLayout(ui)
+				return
+			}
+			row := ui.grid.Rows[sel[0]]
+			s := row.Value.(*lockSession)
+			id := s.id
+			if len(s.blockers) > 0 {
+				id = s.blockers[0]
+			}
+			ui.connUI.showTool(ui.connUI.sessionsUI)
+			ui.connUI.sessionsUI.selectSession(id)
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(
+		&duit.Box{
+			Padding: duit.SpaceXY(4, 2),
+			Margin:  image.Pt(4, 2),
+			Valign:  duit.ValignMiddle,
+			Kids:    duit.NewKids(refresh, jump, ui.message),
+		},
+		duit.NewScroll(ui.gridBox),
+	)
+	return
+}
+
+// load fetches the locks. Called from main loop.
+func (ui *locksUI) load() {
+	ui.message.Text = "loading..."
+	dui.MarkLayout(ui)
+	go ui._load()
+}
+
+// called from outside main loop
+func (ui *locksUI) _load() {
+	lcheck, handle := errorHandler(func(err error) {
+		dui.Call <- func() {
+			ui.message.Text = fmt.Sprintf("error: %s", err)
+			dui.MarkLayout(ui)
+		}
+	})
+	defer handle()
+
+	// each query returns rows of: session, blocking session, lock type, lock mode, object, granted, query
+	var q string
+	switch ui.connUI.config.Type {
+	case "postgres":
+		q = `
+			select
+				a.pid,
+				b.blocker,
+				l.locktype,
+				l.mode,
+				coalesce(l.relation::regclass::text, l.transactionid::text, l.virtualxid, ''),
+				l.granted,
+				a.query
+			from pg_locks l
+			join pg_stat_activity a on l.pid = a.pid
+			left join lateral unnest(pg_blocking_pids(a.pid)) b(blocker) on not l.granted
+			where a.pid <> pg_backend_pid()
+		`
+	case "mysql":
+		q = `
+			select
+				rt.processlist_id,
+				bt.processlist_id,
+				rl.lock_type,
+				rl.lock_mode,
+				concat(rl.object_schema, '.', rl.object_name, coalesce(concat(' ', rl.index_name), '')),
+				false,
+				rt.processlist_info
+			from performance_schema.data_lock_waits w
+			join performance_schema.data_locks rl on w.requesting_engine_lock_id = rl.engine_lock_id
+			join performance_schema.threads rt on w.requesting_thread_id = rt.thread_id
+			join performance_schema.threads bt on w.blocking_thread_id = bt.thread_id
+			union all
+			select
+				bt.processlist_id,
+				null,
+				bl.lock_type,
+				bl.lock_mode,
+				concat(bl.object_schema, '.', bl.object_name, coalesce(concat(' ', bl.index_name), '')),
+				true,
+				bt.processlist_info
+			from performance_schema.data_lock_waits w
+			join performance_schema.data_locks bl on w.blocking_engine_lock_id = bl.engine_lock_id
+			join performance_schema.threads bt on w.blocking_thread_id = bt.thread_id
+		`
+	case "sqlserver":
+		q = `
+			select
+				l.request_session_id,
+				nullif(r.blocking_session_id, 0),
+				l.resource_type,
+				l.request_mode,
+				coalesce(object_name(p.object_id, l.resource_database_id), db_name(l.resource_database_id), ''),
+				case when l.request_status = 'GRANT' then 1 else 0 end,
+				t.text
+			from sys.dm_tran_locks l
+			left join sys.dm_exec_requests r on l.request_session_id = r.session_id
+			left join sys.partitions p on l.resource_associated_entity_id = p.hobt_id
+			outer apply sys.dm_exec_sql_text(r.sql_handle) t
+			where l.request_session_id <> @@spid
+		`
+	default:
+		panic("bad connection type")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	rows, err := ui.connUI.db.QueryContext(ctx, q)
+	lcheck(err, "listing locks")
+	defer rows.Close()
+	sessions := map[string]*lockSession{}
+	for rows.Next() {
+		var id, blocker, lockType, mode, object, query sql.NullString
+		var granted bool
+		err = rows.Scan(&id, &blocker, &lockType, &mode, &object, &granted, &query)
+		lcheck(err, "scanning row")
+		s, ok := sessions[id.String]
+		if !ok {
+			s = &lockSession{id: id.String, query: oneLine(query.String)}
+			sessions[id.String] = s
+		}
+		if blocker.Valid && !containsString(s.blockers, blocker.String) {
+			s.blockers = append(s.blockers, blocker.String)
+		}
+		lock := strings.TrimSpace(fmt.Sprintf("%s %s %s", lockType.String, mode.String, object.String))
+		if !containsString(s.locks, lock) {
+			s.locks = append(s.locks, lock)
+		}
+		s.waiting = s.waiting || !granted
+	}
+	lcheck(rows.Err(), "reading row")
+
+	gridRows := lockTree(sessions)
+
+	dui.Call <- func() {
+		ui.grid.Rows = gridRows
+		if len(gridRows) == 0 {
+			ui.gridBox.Kids = duit.NewKids(middle(label("no locks")))
+		} else {
+			ui.gridBox.Kids = duit.NewKids(ui.grid)
+		}
+		ui.message.Text = fmt.Sprintf("%d sessions with locks, refreshed at %s", len(sessions), time.Now().Format("15:04:05"))
+		dui.MarkLayout(ui)
+	}
+}
+
+// lockTree returns gridrows for the sessions, with sessions that are blocked indented below the sessions blocking them.
+// Sessions not blocked by a known session are the roots. Sessions in a cycle (deadlock) are shown once more, marked.
+func lockTree(sessions map[string]*lockSession) []*duit.Gridrow {
+	blocked := map[string][]*lockSession{}
+	var roots []*lockSession
+	for _, s := range sessions {
+		isRoot := true
+		for _, b := range s.blockers {
+			if _, ok := sessions[b]; ok {
+				blocked[b] = append(blocked[b], s)
+				isRoot = false
+			}
+		}
+		if isRoot {
+			roots = append(roots, s)
+		}
+	}
+	byID := func(l []*lockSession) {
+		sort.Slice(l, func(i, j int) bool {
+			a, _ := strconv.ParseInt(l[i].id, 10, 64)
+			b, _ := strconv.ParseInt(l[j].id, 10, 64)
+			return a < b
+		})
+	}
+	byID(roots)
+
+	var rows []*duit.Gridrow
+	seen := map[string]bool{}
+	var add func(s *lockSession, depth int, path map[string]bool)
+	add = func(s *lockSession, depth int, path map[string]bool) {
+		seen[s.id] = true
+		status := "granted"
+		if s.waiting {
+			status = "waiting"
+		}
+		name := strings.Repeat("    ", depth) + s.id
+		if path[s.id] {
+			rows = append(rows, &duit.Gridrow{
+				Values: []string{name, strings.Join(s.blockers, ", "), "deadlock", strings.Join(s.locks, "; "), s.query},
+				Value:  s,
+			})
+			return
+		}
+		rows = append(rows, &duit.Gridrow{
+			Values: []string{name, strings.Join(s.blockers, ", "), status, strings.Join(s.locks, "; "), s.query},
+			Value:  s,
+		})
+		path[s.id] = true
+		kids := blocked[s.id]
+		byID(kids)
+		for _, k := range kids {
+			add(k, depth+1, path)
+		}
+		delete(path, s.id)
+	}
+	for _, s := range roots {
+		add(s, 0, map[string]bool{})
+	}
+	// sessions only blocked by each other have no root
+	var rest []*lockSession
+	for _, s := range sessions {
+		if !seen[s.id] {
+			rest = append(rest, s)
+		}
+	}
+	byID(rest)
+	for _, s := range rest {
+		if !seen[s.id] {
+			add(s, 0, map[string]bool{})
+		}
+	}
+	return rows
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"image"
+	"strings"
+	"time"
+
+	"github.com/mjl-/duit"
+)
+
+// maximum number of databases a query runs on at the same time
+const multiConcurrency = 4
+
+// multiResult is the result of a query in one database, for multiResultUI.
+type multiResult struct {
+	columns  []resultColumn
+	values   [][]interface{}
+	exec     bool  // statement without result rows, executed
+	affected int64 // rows affected by an executed statement
+	err      error
+}
+
+// multiResultUI runs a query on multiple databases of a connection, showing the status per database
+// and the combined rows, with a leading database column.
+type multiResultUI struct {
+	dbUI    *dbUI // database of the editUI, for the combined result
+	dbNames []string
+	query   string
+
+	message   *duit.Label
+	statuses  *duit.Gridlist // per database
+	result    *resultUI
+	resultBox *duit.Box
+	cancel    context.CancelFunc // cancels the running queries, nil if done
+
+	duit.Box
+}
+
+func newMultiResultUI(dbUI *dbUI, dbNames []string, query string) (ui *multiResultUI) {
+	ui = &multiResultUI{dbUI: dbUI, dbNames: dbNames, query: query}
+	ui.message = &duit.Label{}
+	ui.statuses = &duit.Gridlist{
+		Header:  &duit.Gridrow{Values: []string{"database", "status", "rows", "time"}},
+		Striped: true,
+		Padding: duit.SpaceXY(4, 2),
+		Halign:  []duit.Halign{duit.HalignLeft, duit.HalignLeft, duit.HalignRight, duit.HalignRight},
+	}
+	for _, name := range dbNames {
+		ui.statuses.Rows = append(ui.statuses.Rows, &duit.Gridrow{Values: []string{name, "waiting", "", ""}})
+	}
+	ui.resultBox = &duit.Box{}
+	cancel := &duit.Button{
+		Text: "cancel",
+		Click: func() (e duit.Event) {
+			if ui.cancel != nil {
+				ui.cancel()
+			}
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(
+		&duit.Box{
+			Padding: duit.SpaceXY(4, 2),
+			Margin:  image.Pt(4, 2),
+			Valign:  duit.ValignMiddle,
+			Kids:    duit.NewKids(cancel, ui.message),
+		},
+		&duit.Split{
+			Vertical:   true,
+			Gutter:     1,
+			Background: dui.Gutter,
+			Split: func(height int) []int {
+				first := height / 4
+				return []int{first, height - first}
+			},
+			Kids: duit.NewKids(duit.NewScroll(ui.statuses), ui.resultBox),
+		},
+	)
+	return
+}
+
+func (ui *multiResultUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+// run executes the query on all databases, at most multiConcurrency at a time.
+// The connection of a dbUI is used if it is open, otherwise a temporary connection is made.
+// Called from main loop.
+func (ui *multiResultUI) run() {
+	defer ui.layout()
+	cUI := ui.dbUI.connUI
+	dbs := map[string]*sql.DB{} // open connections of dbUIs
+	for _, lv := range cUI.databases.Values {
+		dUI := lv.Value.(*dbUI)
+		dbs[dUI.dbName] = dUI.db
+	}
+	config := cUI.config
+	ctx, cancel := context.WithCancel(context.Background())
+	ui.cancel = cancel
+	ui.message.Text = fmt.Sprintf("running on %d databases...", len(ui.dbNames))
+
+	results := make([]multiResult, len(ui.dbNames))
+	go func() {
+		defer cancel()
+		sem := make(chan struct{}, multiConcurrency)
+		done := make(chan struct{})
+		for i, name := range ui.dbNames {
+			go func(i int, name string, db *sql.DB) {
+				sem <- struct{}{}
+				defer func() {
+					<-sem
+					done <- struct{}{}
+				}()
+				row := ui.statuses.Rows[i]
+				dui.Call <- func() {
+					row.Values[1] = "running"
+					ui.layout()
+				}
+				start := time.Now()
+				r := ui.runDatabase(ctx, config, name, db)
+				results[i] = r
+				dui.Call <- func() {
+					if r.err != nil {
+						row.Values[1] = fmt.Sprintf("error: %s", oneLine(r.err.Error()))
+					} else if r.exec {
+						row.Values[1] = "ok, executed"
+						row.Values[2] = fmt.Sprintf("%d affected", r.affected)
+					} else {
+						row.Values[1] = "ok"
+						row.Values[2] = fmt.Sprintf("%d", len(r.values))
+					}
+					row.Values[3] = time.Since(start).Round(time.Millisecond).String()
+					ui.layout()
+				}
+			}(i, name, dbs[name])
+		}
+		for range ui.dbNames {
+			<-done
+		}
+		dui.Call <- func() {
+			ui.cancel = nil
+			ui.combine(results)
+		}
+	}()
+}
+
+// runDatabase executes the query in database dbName, using db if not nil.
+// Called from outside main loop.
+func (ui *multiResultUI) runDatabase(ctx context.Context, config connectionConfig, dbName string, db *sql.DB) (r multiResult) {
+	lcheck, handle := errorHandler(func(err error) {
+		r.err = err
+	})
+	defer handle()
+
+	if db == nil {
+		var err error
+		db, err = sql.Open(config.Type, config.connectionString(dbName))
+		lcheck(err, "connecting")
+		defer db.Close()
+	}
+	if !returnsRows(ui.query) {
+		result, err := db.ExecContext(ctx, ui.query)
+		lcheck(err, "executing statement")
+		r.exec = true
+		r.affected, err = result.RowsAffected()
+		lcheck(err, "reading rows affected")
+		return
+	}
+	var stats resultStats
+	r.columns, _, r.values = queryRows(ctx, db, config.Type, ui.query, lcheck, &stats)
+	return
+}
+
+// returnsRows returns whether query is a statement returning rows, as opposed to eg an update or DDL.
+func returnsRows(query string) bool {
+	t := strings.Fields(strings.ToLower(stripComments(query)))
+	if len(t) == 0 {
+		return false
+	}
+	switch strings.TrimLeft(t[0], "(") {
+	case "select", "with", "values", "table", "show", "explain", "describe", "desc", "call", "exec", "execute":
+		return true
+	}
+	// postgres and sqlserver can return rows from insert, update and delete
+	for _, w := range t {
+		if w == "returning" || strings.HasPrefix(w, "output") {
+			return true
+		}
+	}
+	return false
+}
+
+// combine shows the rows of all databases that returned the same columns as the first, with the database as first column.
+// Called from main loop.
+func (ui *multiResultUI) combine(results []multiResult) {
+	defer ui.layout()
+	var columns []resultColumn
+	var values [][]interface{}
+	var first string
+	nok, nrows := 0, 0
+	var nexec, affected int64
+	for i, r := range results {
+		if r.err != nil {
+			continue
+		}
+		if r.exec {
+			nok++
+			nexec++
+			affected += r.affected
+			continue
+		}
+		name := ui.dbNames[i]
+		if columns == nil {
+			first = name
+			columns = append([]resultColumn{{name: "database", kind: kindText}}, r.columns...)
+		} else if !sameColumns(columns[1:], r.columns) {
+			ui.statuses.Rows[i].Values[1] = fmt.Sprintf("error: columns differ from those of %s", first)
+			continue
+		}
+		nok++
+		nrows += len(r.values)
+		for _, row := range r.values {
+			values = append(values, append([]interface{}{name}, row...))
+		}
+	}
+	ui.message.Text = fmt.Sprintf("%d rows from %d of %d databases", nrows, nok, len(results))
+	if nexec > 0 {
+		ui.message.Text = fmt.Sprintf("%d rows affected, %d rows returned, in %d of %d databases", affected, nrows, nok, len(results))
+	}
+	if nok < len(results) {
+		ui.message.Text += fmt.Sprintf(", %d failed", len(results)-nok)
+	}
+	if columns == nil {
+		return
+	}
+	if ui.result == nil {
+		ui.result = newResultUI(ui.dbUI, ui.query)
+		ui.resultBox.Kids = duit.NewKids(ui.result)
+	}
+	ui.result.compute = func() ([]resultColumn, [][]interface{}, error) {
+		return columns, values, nil
+	}
+	ui.result.reload()
+}
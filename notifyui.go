@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"9fans.net/go/draw"
+	"github.com/lib/pq"
+	"github.com/mjl-/duit"
+)
+
+// number of notifications kept in the list, older ones are dropped
+const notifyMax = 1000
+
+// notifyUI listens for postgres notifications on channels, and sends notifications for testing.
+type notifyUI struct {
+	dbUI     *dbUI
+	channels *duit.Field // channel names to listen on, separated by commas or spaces
+	listen   *duit.Button
+	channel  *duit.Field // for sending
+	payload  *duit.Field
+	message  *duit.Label
+	list     *duit.Gridlist // newest first
+
+	listener *pq.Listener // nil if not listening
+
+	duit.Box
+}
+
+func newNotifyUI(dbUI *dbUI) (ui *notifyUI) {
+	ui = &notifyUI{dbUI: dbUI}
+	ui.channels = &duit.Field{
+		Placeholder: "channels to listen on...",
+		Keys: func(k rune, m draw.Mouse) (e duit.Event) {
+			if k == '\n' {
+				e.Consumed = true
+				ui.start()
+			}
+			return
+		},
+	}
+	ui.listen = &duit.Button{
+		Text:     "listen",
+		Colorset: &dui.Primary,
+		Click: func() (e duit.Event) {
+			if ui.listener != nil {
+				ui.stop()
+			} else {
+				ui.start()
+			}
+			return
+		},
+	}
+	ui.channel = &duit.Field{Placeholder: "channel"}
+	ui.payload = &duit.Field{
+		Placeholder: "payload",
+		Keys: func(k rune, m draw.Mouse) (e duit.Event) {
+			if k == '\n' {
+				e.Consumed = true
+				ui.send()
+			}
+			return
+		},
+	}
+	send := &duit.Button{
+		Text: "notify",
+		Click: func() (e duit.Event) {
+			ui.send()
+			return
+		},
+	}
+	clear := &duit.Button{
+		Text: "clear",
+		Click: func() (e duit.Event) {
+			ui.list.Rows = nil
+			ui.layout()
+			return
+		},
+	}
+	ui.message = &duit.Label{}
+	ui.list = &duit.Gridlist{
+		Header:  &duit.Gridrow{Values: []string{"time", "channel", "payload", "pid"}},
+		Striped: true,
+		Padding: duit.SpaceXY(4, 2),
+	}
+	ui.Box.Kids = duit.NewKids(
+		toolbar(
+			&duit.Box{Width: 300, Kids: duit.NewKids(ui.channels)},
+			ui.listen,
+			clear,
+			ui.message,
+		),
+		toolbar(
+			label("send"),
+			&duit.Box{Width: 150, Kids: duit.NewKids(ui.channel)},
+			&duit.Box{Width: 300, Kids: duit.NewKids(ui.payload)},
+			send,
+		),
+		duit.NewScroll(ui.list),
+	)
+	return
+}
+
+func (ui *notifyUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+// start listens on the channels, replacing a current listener.
+// Called from main loop.
+func (ui *notifyUI) start() {
+	defer ui.layout()
+	ui.stop()
+	channels := strings.FieldsFunc(ui.channels.Text, func(c rune) bool {
+		return c == ',' || c == ' '
+	})
+	if len(channels) == 0 {
+		ui.message.Text = "enter channels to listen on"
+		return
+	}
+
+	var l *pq.Listener
+	event := func(ev pq.ListenerEventType, err error) {
+		var msg string
+		switch ev {
+		case pq.ListenerEventConnected:
+			msg = fmt.Sprintf("listening on %s", strings.Join(channels, ", "))
+		case pq.ListenerEventDisconnected:
+			msg = fmt.Sprintf("disconnected: %s; reconnecting...", err)
+		case pq.ListenerEventReconnected:
+			msg = fmt.Sprintf("reconnected, listening on %s; notifications may have been missed", strings.Join(channels, ", "))
+		case pq.ListenerEventConnectionAttemptFailed:
+			msg = fmt.Sprintf("connecting: %s; retrying...", err)
+		}
+		dui.Call <- func() {
+			if ui.listener == l {
+				ui.message.Text = msg
+				ui.layout()
+			}
+		}
+	}
+	l = pq.NewListener(ui.dbUI.connUI.config.connectionString(ui.dbUI.dbName), time.Second, time.Minute, event)
+	ui.listener = l
+	ui.listen.Text = "stop"
+	ui.message.Text = "connecting..."
+
+	go func() {
+		for _, ch := range channels {
+			// blocks until connected
+			err := l.Listen(ch)
+			if err != nil {
+				dui.Call <- func() {
+					if ui.listener == l {
+						ui.stop()
+						ui.message.Text = fmt.Sprintf("listen on %s: %s", ch, err)
+						ui.layout()
+					}
+				}
+				return
+			}
+		}
+	}()
+
+	go func() {
+		// the connection is checked regularly, as lost connections are not always noticed otherwise
+		ticker := time.NewTicker(90 * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case n, ok := <-l.Notify:
+				if !ok {
+					// listener was closed
+					return
+				}
+				if n == nil {
+					// sent after reconnecting
+					continue
+				}
+				dui.Call <- func() {
+					if ui.listener != l {
+						return
+					}
+					row := &duit.Gridrow{
+						Values: []string{time.Now().Format("15:04:05.000"), n.Channel, oneLine(n.Extra), fmt.Sprintf("%d", n.BePid)},
+					}
+					ui.list.Rows = append([]*duit.Gridrow{row}, ui.list.Rows...)
+					if len(ui.list.Rows) > notifyMax {
+						ui.list.Rows = ui.list.Rows[:notifyMax]
+					}
+					ui.layout()
+				}
+			case <-ticker.C:
+				go l.Ping()
+			}
+		}
+	}()
+}
+
+// stop stops listening, if listening.
+// Called from main loop.
+func (ui *notifyUI) stop() {
+	if ui.listener == nil {
+		return
+	}
+	ui.listener.Close()
+	ui.listener = nil
+	ui.listen.Text = "listen"
+	ui.message.Text = "stopped"
+	ui.layout()
+}
+
+// send sends a notification with the payload on the channel, through the connection of the database.
+// Called from main loop.
+func (ui *notifyUI) send() {
+	channel, payload := strings.TrimSpace(ui.channel.Text), ui.payload.Text
+	if channel == "" {
+		ui.message.Text = "enter a channel to notify"
+		ui.layout()
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		_, err := ui.dbUI.db.ExecContext(ctx, "select pg_notify($1, $2)", channel, payload)
+		dui.Call <- func() {
+			if err != nil {
+				ui.message.Text = fmt.Sprintf("notify: %s", err)
+			} else {
+				ui.message.Text = fmt.Sprintf("sent notification on %s", channel)
+			}
+			ui.layout()
+		}
+	}()
+}
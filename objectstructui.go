@@ -0,0 +1,294 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"image"
+
+	"github.com/mjl-/duit"
+)
+
+// objectstructUI shows the structure and source of a function, procedure, trigger or sequence.
+type objectstructUI struct {
+	dbUI      *dbUI
+	kind      string // F, P, TR or S
+	name      string
+	key       string // identifies the object in the structure queries, as returned when listing objects
+	vertical  *duit.Split
+	scrollBox *duit.Box
+	duit.Box
+}
+
+var objectKindNames = map[string]string{
+	"T":  "table",
+	"V":  "view",
+	"F":  "function",
+	"P":  "procedure",
+	"TR": "trigger",
+	"S":  "sequence",
+}
+
+func newObjectStructUI(dbUI *dbUI, kind, name, key string) *objectstructUI {
+	scrollBox := &duit.Box{
+		Height: -1,
+	}
+	scroll := &duit.Scroll{
+		Height: -1,
+		Kid: duit.Kid{
+			UI: scrollBox,
+		},
+	}
+	vertical := &duit.Split{
+		Vertical:   true,
+		Gutter:     1,
+		Background: dui.Gutter,
+		Split: func(height int) []int {
+			first := height / 4
+			return []int{first, height - first}
+		},
+		Kids: duit.NewKids(scroll, nil), // nil will be replaced by an Edit
+	}
+	return &objectstructUI{
+		dbUI:      dbUI,
+		kind:      kind,
+		name:      name,
+		key:       key,
+		vertical:  vertical,
+		scrollBox: scrollBox,
+	}
+}
+
+func (ui *objectstructUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+func (ui *objectstructUI) status(msg string) {
+	retry := &duit.Button{
+		Text: "retry",
+		Click: func() (e duit.Event) {
+			ui.init()
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(middle(label(msg), retry))
+	ui.layout()
+}
+
+// called from main loop
+func (ui *objectstructUI) init() {
+	ctx, cancelQueryFunc := context.WithCancel(context.Background())
+
+	cancel := &duit.Button{
+		Text: "cancel",
+		Click: func() (e duit.Event) {
+			cancelQueryFunc()
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(middle(label("executing query..."), cancel))
+	ui.layout()
+
+	go ui._load(ctx, cancelQueryFunc)
+}
+
+// called from outside main loop
+func (ui *objectstructUI) _load(ctx context.Context, cancelQueryFunc func()) {
+	defer cancelQueryFunc()
+
+	lcheck, handle := errorHandler(func(err error) {
+		dui.Call <- func() {
+			ui.status(fmt.Sprintf("error: %s", err))
+		}
+	})
+	defer handle()
+
+	// each query returns signature, language, return type, owning table and source
+	var q string
+	var args []interface{}
+	switch ui.dbUI.connUI.config.Type {
+	case "postgres":
+		switch ui.kind {
+		case "F", "P":
+			q = `
+				select
+					pg_get_function_identity_arguments(p.oid),
+					l.lanname,
+					coalesce(pg_get_function_result(p.oid), ''),
+					'',
+					pg_get_functiondef(p.oid)
+				from pg_proc p
+				join pg_language l on p.prolang = l.oid
+				where p.oid = $1::oid
+			`
+		case "TR":
+			q = `
+				select
+					'',
+					'',
+					'',
+					t.tgrelid::regclass::text,
+					pg_get_triggerdef(t.oid, true) || E';\n\n' || pg_get_functiondef(t.tgfoid)
+				from pg_trigger t
+				where t.oid = $1::oid
+			`
+		case "S":
+			q = `
+				select
+					'',
+					'',
+					data_type,
+					'',
+					concat('create sequence ', $1::text, ' as ', data_type, ' start ', start_value, ' increment ', increment, ' minvalue ', minimum_value, ' maxvalue ', maximum_value, case when cycle_option = 'YES' then ' cycle' end, ';')
+				from information_schema.sequences
+				where sequence_schema || '.' || sequence_name = $1::text
+			`
+		}
+		args = append(args, ui.key)
+	case "mysql":
+		switch ui.kind {
+		case "F", "P":
+			q = `
+				select
+					(
+						select group_concat(concat_ws(' ', p.parameter_mode, p.parameter_name, p.dtd_identifier) order by p.ordinal_position separator ', ')
+						from information_schema.parameters p
+						where p.specific_schema = r.routine_schema and p.specific_name = r.specific_name and p.ordinal_position > 0
+					),
+					r.routine_body,
+					coalesce(r.dtd_identifier, ''),
+					'',
+					r.routine_definition
+				from information_schema.routines r
+				where r.routine_schema = ? and r.routine_name = ? and r.routine_type = ?
+			`
+		case "TR":
+			q = `
+				select
+					'',
+					'',
+					'',
+					event_object_table,
+					concat(action_timing, ' ', event_manipulation, ' for each ', action_orientation, '\n', action_statement)
+				from information_schema.triggers
+				where trigger_schema = ? and trigger_name = ?
+			`
+		}
+		args = append(args, ui.dbUI.dbName, ui.key)
+		// a function and a procedure can have the same name
+		switch ui.kind {
+		case "F":
+			args = append(args, "FUNCTION")
+		case "P":
+			args = append(args, "PROCEDURE")
+		}
+	case "sqlserver":
+		switch ui.kind {
+		case "F", "P":
+			q = `
+				select
+					stuff((
+						select concat(', ', p.name, ' ', type_name(p.user_type_id), case when p.is_output = 1 then ' output' end)
+						from sys.parameters p
+						where p.object_id = o.object_id and p.parameter_id > 0
+						order by p.parameter_id
+						for xml path('')
+					), 1, 2, ''),
+					'sql',
+					coalesce(
+						(select type_name(p.user_type_id) from sys.parameters p where p.object_id = o.object_id and p.parameter_id = 0),
+						case when o.type in ('IF', 'TF') then 'table' end,
+						''
+					),
+					'',
+					object_definition(o.object_id)
+				from sys.objects o
+				where o.object_id = @key
+			`
+		case "TR":
+			q = `
+				select
+					'',
+					'',
+					'',
+					concat(object_schema_name(parent_id), '.', object_name(parent_id)),
+					object_definition(object_id)
+				from sys.triggers
+				where object_id = @key
+			`
+		case "S":
+			q = `
+				select
+					'',
+					'',
+					type_name(user_type_id),
+					'',
+					concat(
+						'create sequence ', schema_name(schema_id), '.', name,
+						' as ', type_name(user_type_id),
+						' start with ', cast(start_value as varchar(40)),
+						' increment by ', cast(increment as varchar(40)),
+						' minvalue ', cast(minimum_value as varchar(40)),
+						' maxvalue ', cast(maximum_value as varchar(40)),
+						case when is_cycling = 1 then ' cycle' end,
+						';'
+					)
+				from sys.sequences
+				where object_id = @key
+			`
+		}
+		args = append(args, sql.Named("key", ui.key))
+	default:
+		panic("bad connection type")
+	}
+	if q == "" {
+		lcheck(fmt.Errorf("no structure available for %s", objectKindNames[ui.kind]), "fetching structure")
+	}
+
+	var signature, language, returns, table, source sql.NullString
+	err := ui.dbUI.db.QueryRowContext(ctx, q, args...).Scan(&signature, &language, &returns, &table, &source)
+	lcheck(err, "fetching structure")
+
+	properties := []string{
+		"kind", objectKindNames[ui.kind],
+		"name", ui.name,
+	}
+	add := func(k string, v sql.NullString) {
+		if v.String != "" {
+			properties = append(properties, k, v.String)
+		}
+	}
+	if ui.kind == "F" || ui.kind == "P" {
+		properties = append(properties, "signature", "("+signature.String+")")
+	}
+	add("language", language)
+	add("returns", returns)
+	add("table", table)
+	var propertyUIs []duit.UI
+	for _, s := range properties {
+		propertyUIs = append(propertyUIs, label(s))
+	}
+
+	edit := readOnlyEdit(source.String)
+
+	dui.Call <- func() {
+		ui.scrollBox.Padding = duit.Space{Top: duit.ScrollbarSize, Right: duit.ScrollbarSize, Bottom: 6, Left: duit.ScrollbarSize}
+		ui.scrollBox.Margin = image.Pt(0, 6)
+		ui.scrollBox.Kids = duit.NewKids(
+			&duit.Grid{
+				Columns: 2,
+				Width:   -1,
+				Padding: []duit.Space{
+					duit.Space{Top: 1, Right: 4, Bottom: 1, Left: 0},
+					duit.Space{Top: 1, Right: 0, Bottom: 2, Left: 4},
+				},
+				Kids: duit.NewKids(propertyUIs...),
+			},
+			&duit.Label{Font: bold, Text: "source"},
+		)
+		ui.vertical.Kids[1].UI = edit
+		ui.Box.Kids = duit.NewKids(ui.vertical)
+		ui.Box.Kids[0].ID = "objectstruct"
+		ui.layout()
+	}
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/mjl-/duit"
+)
+
+// dbObject is a table, view, routine, trigger or sequence in a database, as listed by dbUI.
+type dbObject struct {
+	Kind string // T for table, V view, F function, P procedure, TR trigger, S sequence
+	Name string
+	Key  string
+}
+
+// objectNode is a schema, a group of objects of one kind, or an object, in the tree of objects of dbUI.
+type objectNode struct {
+	label    string
+	object   dbObject // for objects
+	expanded bool
+	children []*objectNode // nil for objects
+	row      *duit.Gridrow // for groups, Value is the objectNode, for objects the UI showing the object
+}
+
+var objectKinds = []struct {
+	kind  string
+	label string
+}{
+	{"T", "tables"},
+	{"V", "views"},
+	{"F", "functions"},
+	{"P", "procedures"},
+	{"TR", "triggers"},
+	{"S", "sequences"},
+}
+
+// systemSchema returns whether schema holds the system catalog, these schemas start collapsed.
+func systemSchema(connType, schema string) bool {
+	switch connType {
+	case "postgres":
+		return schema == "pg_catalog" || schema == "information_schema" || strings.HasPrefix(schema, "pg_toast")
+	case "sqlserver":
+		return schema == "sys" || schema == "INFORMATION_SCHEMA"
+	}
+	return false
+}
+
+func newGroupNode(label string, expanded bool) *objectNode {
+	n := &objectNode{label: label, expanded: expanded}
+	n.row = &duit.Gridrow{Values: []string{"", ""}, Value: n}
+	return n
+}
+
+// buildObjectTree groups objects by schema and kind. For mysql, which has no schemas within a database, objects are only grouped by kind.
+// uis holds the UI for each object.
+func buildObjectTree(connType string, objects []dbObject, uis []duit.UI) (tree []*objectNode) {
+	schemas := map[string]*objectNode{}
+	kinds := map[*objectNode]map[string]*objectNode{}
+	root := &objectNode{}
+	for i, obj := range objects {
+		parent := root
+		name := obj.Name
+		if connType != "mysql" {
+			t := strings.SplitN(obj.Name, ".", 2)
+			if len(t) == 2 {
+				schema := schemas[t[0]]
+				if schema == nil {
+					schema = newGroupNode(t[0], !systemSchema(connType, t[0]))
+					schemas[t[0]] = schema
+					root.children = append(root.children, schema)
+				}
+				parent = schema
+				name = t[1]
+			}
+		}
+		if kinds[parent] == nil {
+			kinds[parent] = map[string]*objectNode{}
+		}
+		group := kinds[parent][obj.Kind]
+		if group == nil {
+			label := obj.Kind
+			for _, k := range objectKinds {
+				if k.kind == obj.Kind {
+					label = k.label
+				}
+			}
+			group = newGroupNode(label, true)
+			kinds[parent][obj.Kind] = group
+		}
+		group.children = append(group.children, &objectNode{
+			label:  name,
+			object: obj,
+			row: &duit.Gridrow{
+				Values: []string{obj.Kind + " ", ""},
+				Value:  uis[i],
+			},
+		})
+	}
+
+	// kinds in fixed order, instead of order of first appearance
+	order := func(parent *objectNode) (l []*objectNode) {
+		for _, k := range objectKinds {
+			if g := kinds[parent][k.kind]; g != nil {
+				l = append(l, g)
+			}
+		}
+		return
+	}
+	if connType == "mysql" {
+		return order(root)
+	}
+	for _, schema := range root.children {
+		schema.children = order(schema)
+	}
+	return root.children
+}
+
+// objectRows returns the rows to show for the tree. With a non-empty filter, only objects and groups
+// matching the filter (case-insensitive) are returned, along with their parents, expanded.
+func objectRows(tree []*objectNode, filter string) (rows []*duit.Gridrow) {
+	filter = strings.ToLower(filter)
+	var add func(n *objectNode, depth int, all bool) bool
+	add = func(n *objectNode, depth int, all bool) bool {
+		all = all || filter == "" || strings.Contains(strings.ToLower(n.label), filter)
+		if n.children == nil {
+			if all {
+				n.row.Values[1] = strings.Repeat("  ", depth) + n.label
+				rows = append(rows, n.row)
+			}
+			return all
+		}
+
+		// add the group row now, remove it again if nothing under it matched
+		index := len(rows)
+		rows = append(rows, n.row)
+		matched := false
+		if !all || n.expanded {
+			for _, c := range n.children {
+				if add(c, depth+1, all) {
+					matched = true
+				}
+			}
+		}
+		if !all && !matched {
+			rows = rows[:index]
+			return false
+		}
+		marker := "+"
+		if matched {
+			marker = "-"
+		}
+		n.row.Values = []string{marker, strings.Repeat("  ", depth) + n.label}
+		return true
+	}
+	for _, n := range tree {
+		add(n, 0, false)
+	}
+	return
+}
+
+// walkObjects calls fn for each object, depth-first.
+func walkObjects(tree []*objectNode, fn func(n *objectNode)) {
+	for _, n := range tree {
+		if n.children == nil {
+			fn(n)
+		} else {
+			walkObjects(n.children, fn)
+		}
+	}
+}
+
+// copyExpanded expands and collapses the groups in tree like the groups with the same path in old.
+func copyExpanded(old, tree []*objectNode) {
+	for _, o := range old {
+		for _, n := range tree {
+			if n.children != nil && o.children != nil && n.label == o.label {
+				n.expanded = o.expanded
+				copyExpanded(o.children, n.children)
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+// aggregates for pivots, in the order shown
+var pivotAggregates = []string{"sum", "count", "avg", "min", "max"}
+
+// pivotCell accumulates the non-NULL values for a cell of a pivot.
+type pivotCell struct {
+	n        int64
+	sum      *big.Rat // nil if a value was not numeric
+	min, max interface{}
+}
+
+func newPivotCell() *pivotCell {
+	return &pivotCell{sum: new(big.Rat)}
+}
+
+func (c *pivotCell) add(kind valueKind, v interface{}) {
+	if v == nil {
+		return
+	}
+	c.n++
+	if c.sum != nil {
+		if r, ok := numericValue(kind, v); ok {
+			c.sum.Add(c.sum, r)
+		} else {
+			c.sum = nil
+		}
+	}
+	if c.min == nil || compareValues(kind, v, c.min) < 0 {
+		c.min = v
+	}
+	if c.max == nil || compareValues(kind, v, c.max) > 0 {
+		c.max = v
+	}
+}
+
+// value returns the aggregate of the cell, nil if it has no values.
+func (c *pivotCell) value(aggregate string) interface{} {
+	if c == nil || c.n == 0 {
+		if aggregate == "count" {
+			return int64(0)
+		}
+		return nil
+	}
+	switch aggregate {
+	case "sum":
+		if c.sum.IsInt() {
+			return c.sum.RatString()
+		}
+		return c.sum.FloatString(4)
+	case "count":
+		return c.n
+	case "avg":
+		return new(big.Rat).Quo(c.sum, new(big.Rat).SetInt64(c.n)).FloatString(4)
+	case "min":
+		return c.min
+	case "max":
+		return c.max
+	}
+	panic("bad aggregate")
+}
+
+// pivot returns a crosstab of rows, indices into values and texts, with the distinct values of column rowKey as rows,
+// of column colKey as columns, and the aggregate of column value in the cells, with totals as last row and column.
+func pivot(columns []resultColumn, values [][]interface{}, texts [][]string, rows []int, rowKey, colKey, value int, aggregate string) ([]resultColumn, [][]interface{}, error) {
+	kind := columns[value].kind
+	if aggregate == "sum" || aggregate == "avg" {
+		for _, i := range rows {
+			if v := values[i][value]; v != nil {
+				if _, ok := numericValue(kind, v); !ok {
+					return nil, nil, fmt.Errorf("%s needs a numeric value column, %s is not", aggregate, columns[value].name)
+				}
+			}
+		}
+	}
+
+	// distinct keys by text, ordered by their first raw value
+	type key struct {
+		text string
+		v    interface{}
+	}
+	keys := func(col int) (l []key) {
+		seen := map[string]bool{}
+		for _, i := range rows {
+			t := texts[i][col]
+			if !seen[t] {
+				seen[t] = true
+				l = append(l, key{t, values[i][col]})
+			}
+		}
+		sort.SliceStable(l, func(i, j int) bool {
+			return compareValues(columns[col].kind, l[i].v, l[j].v) < 0
+		})
+		return
+	}
+	rowKeys, colKeys := keys(rowKey), keys(colKey)
+
+	cells := map[[2]string]*pivotCell{}
+	cell := func(r, c string) *pivotCell {
+		k := [2]string{r, c}
+		if cells[k] == nil {
+			cells[k] = newPivotCell()
+		}
+		return cells[k]
+	}
+	// totals use keys that cannot occur as text, a NUL byte
+	const total = "\x00"
+	for _, i := range rows {
+		r, c, v := texts[i][rowKey], texts[i][colKey], values[i][value]
+		cell(r, c).add(kind, v)
+		cell(r, total).add(kind, v)
+		cell(total, c).add(kind, v)
+		cell(total, total).add(kind, v)
+	}
+
+	resultKind := kindNumeric
+	switch aggregate {
+	case "count":
+		resultKind = kindOther
+	case "min", "max":
+		resultKind = kind
+	}
+	ncols := []resultColumn{{name: columns[rowKey].name, kind: kindText}}
+	for _, k := range colKeys {
+		ncols = append(ncols, resultColumn{name: k.text, kind: resultKind})
+	}
+	ncols = append(ncols, resultColumn{name: "total", kind: resultKind})
+
+	rowKeys = append(rowKeys, key{total, nil})
+	colKeys = append(colKeys, key{total, nil})
+	var nvalues [][]interface{}
+	for _, rk := range rowKeys {
+		label := rk.text
+		if rk.text == total {
+			label = "total"
+		}
+		row := []interface{}{label}
+		for _, ck := range colKeys {
+			row = append(row, cells[[2]string{rk.text, ck.text}].value(aggregate))
+		}
+		nvalues = append(nvalues, row)
+	}
+	return ncols, nvalues, nil
+}
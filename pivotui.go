@@ -0,0 +1,149 @@
+package main
+
+import (
+	"image"
+
+	"github.com/mjl-/duit"
+)
+
+// pivotUI shows a crosstab of the rows of a resultUI, as filtered in its grid, in a resultUI of its own.
+type pivotUI struct {
+	resultUI  *resultUI
+	rowKey    *duit.Gridlist // column with the keys of the rows
+	colKey    *duit.Gridlist // column with the keys of the columns
+	value     *duit.Gridlist // column to aggregate
+	aggregate *duit.Buttongroup
+	result    *resultUI // nil until the first pivot
+	resultBox *duit.Box
+	message   *duit.Label
+
+	duit.Box
+}
+
+func newPivotUI(rUI *resultUI) (ui *pivotUI) {
+	ui = &pivotUI{resultUI: rUI}
+	list := func(header string) *duit.Gridlist {
+		return &duit.Gridlist{
+			Header:  &duit.Gridrow{Values: []string{header}},
+			Striped: true,
+			Padding: duit.SpaceXY(4, 2),
+			Changed: func(index int) (e duit.Event) {
+				ui.pivot()
+				return
+			},
+		}
+	}
+	ui.rowKey = list("rows")
+	ui.colKey = list("columns")
+	ui.value = list("value")
+	ui.aggregate = &duit.Buttongroup{
+		Texts: pivotAggregates,
+		Changed: func(index int) (e duit.Event) {
+			ui.pivot()
+			return
+		},
+	}
+	ui.resultBox = &duit.Box{}
+	ui.message = &duit.Label{}
+	ui.Box.Kids = duit.NewKids(
+		&duit.Split{
+			Gutter:     1,
+			Background: dui.Gutter,
+			Split: func(width int) []int {
+				first := dui.Scale(200)
+				if first > width/3 {
+					first = width / 3
+				}
+				return []int{first, width - first}
+			},
+			Kids: duit.NewKids(
+				duit.NewScroll(&duit.Box{Kids: duit.NewKids(ui.rowKey, ui.colKey, ui.value)}),
+				&duit.Box{
+					Kids: duit.NewKids(
+						&duit.Box{
+							Padding: duit.SpaceXY(4, 2),
+							Margin:  image.Pt(4, 2),
+							Valign:  duit.ValignMiddle,
+							Kids:    duit.NewKids(ui.aggregate, ui.message),
+						},
+						ui.resultBox,
+					),
+				},
+			),
+		},
+	)
+	return
+}
+
+func (ui *pivotUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+// init fills the column lists, keeping the selected columns if they are still present, and computes the pivot.
+// By default the rows are keyed by the first column, the columns by the second, and the last column is aggregated.
+// Called from main loop.
+func (ui *pivotUI) init() {
+	rUI := ui.resultUI
+	n := len(rUI.columns)
+	fill := func(l *duit.Gridlist, def int) {
+		selected := ""
+		for _, row := range l.Rows {
+			if row.Selected {
+				selected = row.Values[0]
+			}
+		}
+		first := len(l.Rows) == 0
+		l.Rows = nil
+		for i, c := range rUI.columns {
+			l.Rows = append(l.Rows, &duit.Gridrow{
+				Selected: c.name == selected || first && i == def,
+				Values:   []string{c.name},
+				Value:    i,
+			})
+		}
+	}
+	colDef := 1
+	if n < 2 {
+		colDef = 0
+	}
+	fill(ui.rowKey, 0)
+	fill(ui.colKey, colDef)
+	fill(ui.value, n-1)
+	ui.pivot()
+}
+
+// pivot computes the crosstab for the selected columns.
+// Called from main loop.
+func (ui *pivotUI) pivot() {
+	defer ui.layout()
+	column := func(l *duit.Gridlist) int {
+		sel := l.Selected()
+		if len(sel) != 1 {
+			return -1
+		}
+		return l.Rows[sel[0]].Value.(int)
+	}
+	rowKey, colKey, value := column(ui.rowKey), column(ui.colKey), column(ui.value)
+	if rowKey < 0 || colKey < 0 || value < 0 {
+		ui.message.Text = "select columns for rows, columns and value"
+		return
+	}
+	ui.message.Text = ""
+
+	// the slices are replaced, not modified, when the result is reloaded, so we can compute outside the main loop
+	rUI := ui.resultUI
+	columns, values, texts := rUI.columns, rUI.values, rUI.texts
+	var rows []int
+	for _, row := range rUI.grid.Rows {
+		rows = append(rows, row.Value.(int))
+	}
+	aggregate := pivotAggregates[ui.aggregate.Selected]
+	if ui.result == nil {
+		ui.result = newResultUI(rUI.dbUI, "")
+		ui.resultBox.Kids = duit.NewKids(ui.result)
+	}
+	ui.result.compute = func() ([]resultColumn, [][]interface{}, error) {
+		return pivot(columns, values, texts, rows, rowKey, colKey, value, aggregate)
+	}
+	ui.result.reload()
+}
@@ -2,17 +2,63 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"image"
 	"reflect"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/mjl-/duit"
 )
 
+// resultColumn describes a column of a resultset.
+type resultColumn struct {
+	name   string
+	dbType string // as returned by the driver, eg VARCHAR or BYTEA
+	kind   valueKind
+}
+
 type resultUI struct {
-	dbUI  *dbUI
-	query string
-	grid  *duit.Gridlist
+	dbUI    *dbUI
+	query   string
+	compute func() ([]resultColumn, [][]interface{}, error) // if set, computes the rows instead of executing query, eg for a pivot
+	table   string                                          // table the query selects from, for generated statements; empty for queries
+	grid    *duit.Gridlist
+	columns []resultColumn
+	values  [][]interface{} // raw values per row, nil for NULL; gridrows hold their index
+	texts   [][]string      // formatted values per row
+	rows    []*duit.Gridrow // one per row, grid.Rows holds those matching the filter, in sort order
+	halign  []duit.Halign   // per column
+
+	// client-side view of the result, applied by refresh
+	display    []int // indices of visible columns, in display order
+	sortCol    int   // column index to sort on, -1 for query order
+	sortDesc   bool
+	frozen     bool // first displayed column stays visible when shifting columns
+	offset     int  // number of (non-frozen) displayed columns shifted out on the left
+	filter     *duit.Field
+	filterText string // initial filter, for setting a filter before the result is loaded
+	message    *duit.Label
+	stats      resultStats
+
+	loading    bool
+	pending    bool               // reload requested while loading, started when the load is done
+	cancelLoad context.CancelFunc // cancels the running load, nil if none
+	refreshBut *duit.Button       // "cancel" while refreshing
+	interval   *duit.Field        // auto-refresh interval in seconds, empty or 0 disables
+	stop       chan struct{}      // closed to stop auto-refresh, nil if not running
+
+	split      *duit.Split // grid, summary, chart or pivot on the left, value inspector on the right
+	gridScroll *duit.Scroll
+	summaryUI  *summaryUI
+	chartUI    *chartUI
+	pivotUI    *pivotUI
+	valueUI    *valueUI
+	columnsUI  *columnsUI
+	columnBox  *duit.Box // holds columnsUI when shown
 
 	duit.Box
 }
@@ -31,28 +77,49 @@ func (ui *resultUI) layout() {
 
 func (ui *resultUI) status(msg string) {
 	defer ui.layout()
+	ui.grid = nil // a next load builds a new view
 	retry := &duit.Button{
 		Text: "retry",
 		Click: func() (e duit.Event) {
-			go ui.load()
+			go ui.load(ui.compute)
 			return
 		},
 	}
 	ui.Box.Kids = duit.NewKids(middle(label(msg), retry))
+	ui.loadDone()
 }
 
-func (ui *resultUI) load() {
+// load executes the query, or calls compute if not nil, and shows the rows.
+// compute is passed in from the main loop, it can be replaced while loading.
+// Called from outside main loop.
+func (ui *resultUI) load(compute func() ([]resultColumn, [][]interface{}, error)) {
+	ctx, cancelQueryFunc := context.WithCancel(context.Background())
+	defer cancelQueryFunc()
 	lcheck, handle := errorHandler(func(err error) {
 		dui.Call <- func() {
+			if ui.grid != nil && ctx.Err() == context.Canceled {
+				// refresh was canceled, keep showing the current rows
+				ui.pending = false
+				ui.loadDone()
+				ui.message.Text = fmt.Sprintf("%s; refresh canceled", ui.stats)
+				return
+			}
 			ui.status(fmt.Sprintf("error: %s", err))
 		}
 	})
 	defer handle()
 
 	status := label("executing query...")
-	ctx, cancelQueryFunc := context.WithCancel(context.Background())
-	defer cancelQueryFunc()
 	dui.Call <- func() {
+		ui.loading = true
+		ui.cancelLoad = cancelQueryFunc
+		if ui.grid != nil {
+			// refreshing, keep showing the current rows
+			ui.message.Text = "refreshing..."
+			ui.refreshBut.Text = "cancel"
+			ui.layout()
+			return
+		}
 		cancel := &duit.Button{
 			Text: "cancel",
 			Click: func() (e duit.Event) {
@@ -82,10 +149,263 @@ func (ui *resultUI) load() {
 		}
 	}()
 
-	rows, err := ui.dbUI.db.QueryContext(ctx, ui.query)
+	var stats resultStats
+	var columns []resultColumn
+	var halign []duit.Halign
+	var values [][]interface{}
+	if compute != nil {
+		var err error
+		columns, values, err = compute()
+		lcheck(err, "computing rows")
+		halign = make([]duit.Halign, len(columns))
+		for i, c := range columns {
+			if c.kind != kindText && len(columns) > 1 {
+				halign[i] = duit.HalignRight
+			}
+		}
+	} else {
+		columns, halign, values = queryRows(ctx, ui.dbUI.db, ui.dbUI.connUI.config.Type, ui.query, lcheck, &stats)
+	}
+	var primaryKey []string
+	if ui.table != "" {
+		// without a primary key, the selection is not kept on refresh
+		_, primaryKey, _ = loadTableColumns(ctx, ui.dbUI.db, ui.dbUI.connUI.config.Type, ui.dbUI.dbName, ui.table)
+	}
+
+	formatter := newValueFormatter(ui.dbUI.connUI.config.Format)
+	var texts [][]string
+	gridRows := []*duit.Gridrow{}
+	for i, row := range values {
+		l := make([]string, len(row))
+		for j, v := range row {
+			l[j] = formatter.format(columns[j].kind, v)
+			stats.bytes += valueSize(v)
+		}
+		texts = append(texts, l)
+		gridRows = append(gridRows, &duit.Gridrow{Value: i})
+	}
+	stats.rows = len(values)
+
+	dui.Call <- func() {
+		if len(gridRows) == 0 {
+			ui.status(fmt.Sprintf("empty resultset"))
+			return
+		}
+
+		if ui.grid != nil && sameColumns(ui.columns, columns) {
+			// keep the view: filter, sort, columns, selection and scroll position
+			defer ui.loadDone()
+			if keys := keyColumns(columns, primaryKey); keys != nil {
+				selected := map[string]bool{}
+				for _, row := range ui.rows {
+					if row.Selected {
+						selected[rowKey(ui.texts[row.Value.(int)], keys)] = true
+					}
+				}
+				for _, row := range gridRows {
+					row.Selected = selected[rowKey(texts[row.Value.(int)], keys)]
+				}
+			}
+			ui.stats = stats
+			ui.values = values
+			ui.texts = texts
+			ui.rows = gridRows
+			ui.refresh()
+			ui.selectionChanged()
+			switch ui.split.Kids[0].UI {
+			case ui.summaryUI:
+				ui.summaryUI.init()
+			case ui.chartUI:
+				ui.chartUI.init()
+			case ui.pivotUI:
+				ui.pivotUI.init()
+			}
+			ui.message.Text = fmt.Sprintf("%s; refreshed at %s", stats, time.Now().Format("15:04:05"))
+			return
+		}
+
+		defer ui.loadDone()
+		ui.stats = stats
+		ui.columns = columns
+		ui.values = values
+		ui.texts = texts
+		ui.rows = gridRows
+		ui.halign = halign
+		ui.split = nil // a new view, with new summary, chart and pivot, is made below
+		ui.display = make([]int, len(columns))
+		for i := range ui.display {
+			ui.display[i] = i
+		}
+		ui.sortCol = -1
+		ui.grid = &duit.Gridlist{
+			Header:   &duit.Gridrow{},
+			Multiple: true,
+			Striped:  true,
+			Padding:  duit.SpaceXY(4, 4),
+			Changed: func(index int) (e duit.Event) {
+				ui.selectionChanged()
+				return
+			},
+		}
+		ui.filter = &duit.Field{
+			Text:        ui.filterText,
+			Placeholder: "filter rows...",
+			Changed: func(text string) (e duit.Event) {
+				ui.filterText = text
+				ui.refresh()
+				return
+			},
+		}
+		ui.refresh()
+
+		ui.columnsUI = newColumnsUI(ui)
+		ui.columnBox = &duit.Box{}
+		columnsButton := &duit.Button{
+			Text: "columns",
+			Click: func() (e duit.Event) {
+				if len(ui.columnBox.Kids) == 0 {
+					ui.columnsUI.init()
+					ui.columnBox.Kids = duit.NewKids(&duit.Scroll{Height: 200, Kid: duit.Kid{UI: ui.columnsUI}})
+				} else {
+					ui.columnBox.Kids = nil
+				}
+				ui.layout()
+				return
+			},
+		}
+		freeze := &duit.Checkbox{
+			Checked: ui.frozen,
+			Changed: func() (e duit.Event) {
+				ui.frozen = !ui.frozen
+				ui.refresh()
+				return
+			},
+		}
+		shift := func(delta int) *duit.Button {
+			text := "<"
+			if delta > 0 {
+				text = ">"
+			}
+			return &duit.Button{
+				Text: text,
+				Click: func() (e duit.Event) {
+					ui.shift(delta)
+					return
+				},
+			}
+		}
+		ui.message = &duit.Label{Text: stats.String()}
+		ui.refreshBut = &duit.Button{
+			Text: "refresh",
+			Click: func() (e duit.Event) {
+				if ui.cancelLoad != nil {
+					ui.cancelLoad()
+				} else {
+					ui.reload()
+				}
+				return
+			},
+		}
+		if ui.interval == nil {
+			ui.interval = &duit.Field{
+				Placeholder: "off",
+				Changed: func(text string) (e duit.Event) {
+					ui.startTicker()
+					return
+				},
+			}
+		}
+		ui.summaryUI = newSummaryUI(ui)
+		ui.chartUI = newChartUI(ui)
+		ui.pivotUI = newPivotUI(ui)
+		// view returns a button that shows kid instead of the grid, or the grid again
+		view := func(text string, kid duit.UI, init func()) *duit.Button {
+			return &duit.Button{
+				Text: text,
+				Click: func() (e duit.Event) {
+					k := ui.split.Kids[0]
+					if k.UI == kid {
+						k.UI = ui.gridScroll
+					} else {
+						k.UI = kid
+						init()
+					}
+					ui.layout()
+					return
+				},
+			}
+		}
+		copyButtons := []duit.UI{label("copy as")}
+		for _, format := range copyFormats {
+			format := format
+			copyButtons = append(copyButtons, &duit.Button{
+				Text: format,
+				Click: func() (e duit.Event) {
+					defer ui.layout()
+					text, n, err := ui.copyText(format)
+					if err != nil {
+						ui.message.Text = fmt.Sprintf("%s; copy: %s", ui.stats, err)
+						return
+					}
+					dui.WriteSnarf([]byte(text))
+					ui.message.Text = fmt.Sprintf("%s; copied %d rows as %s", ui.stats, n, format)
+					return
+				},
+			})
+		}
+		toolbar := &duit.Box{
+			Padding: duit.SpaceXY(4, 2),
+			Margin:  image.Pt(4, 2),
+			Valign:  duit.ValignMiddle,
+			Kids: duit.NewKids(
+				&duit.Box{Width: 200, Kids: duit.NewKids(ui.filter)},
+				ui.refreshBut,
+				label("every"),
+				&duit.Box{Width: 40, Kids: duit.NewKids(ui.interval)},
+				label("seconds"),
+				columnsButton,
+				view("summarize", ui.summaryUI, ui.summaryUI.init),
+				view("chart", ui.chartUI, ui.chartUI.init),
+				view("pivot", ui.pivotUI, ui.pivotUI.init),
+				shift(-1),
+				shift(1),
+				freeze,
+				label("freeze first column"),
+				&duit.Box{
+					Margin: image.Pt(4, 0),
+					Valign: duit.ValignMiddle,
+					Kids:   duit.NewKids(copyButtons...),
+				},
+				ui.message,
+			),
+		}
+
+		ui.valueUI = newValueUI(ui)
+		ui.gridScroll = duit.NewScroll(ui.grid)
+		ui.split = &duit.Split{
+			Gutter:     1,
+			Background: dui.Gutter,
+			Split: func(width int) []int {
+				return ui.splitDimensions(width)
+			},
+			Kids: duit.NewKids(ui.gridScroll, ui.valueUI),
+		}
+		ui.Box.Kids = duit.NewKids(toolbar, ui.columnBox, ui.split)
+		ui.layout()
+	}
+}
+
+// queryRows executes query on db of connection type connType and reads its rows, setting the timings in stats.
+// Called from outside main loop.
+func queryRows(ctx context.Context, db *sql.DB, connType, query string, lcheck func(error, string), stats *resultStats) (columns []resultColumn, halign []duit.Halign, values [][]interface{}) {
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, query)
 	lcheck(err, "executing query")
 	defer rows.Close()
 
+	stats.exec = time.Since(start)
+	start = time.Now()
+
 	colNames, err := rows.Columns()
 	lcheck(err, "reading column names")
 
@@ -96,64 +416,363 @@ func (ui *resultUI) load() {
 		lcheck(fmt.Errorf("no columns in result"), "reading result column types")
 	}
 
-	halign := make([]duit.Halign, len(colTypes))
-
-	vals := make([]interface{}, len(colTypes))
-	isBinary := make([]bool, len(colTypes))
+	halign = make([]duit.Halign, len(colTypes))
+	columns = make([]resultColumn, len(colTypes))
 	for i, t := range colTypes {
-		isBinary[i] = t.DatabaseTypeName() == "BYTEA" // postgres
+		kind := columnKind(connType, t.DatabaseTypeName())
+		columns[i] = resultColumn{
+			name:   colNames[i],
+			dbType: t.DatabaseTypeName(),
+			kind:   kind,
+		}
 		tt := t.ScanType()
-		vals[i] = reflect.New(reflect.PtrTo(tt)).Interface()
-		if tt.Kind() == reflect.String || len(colTypes) == 1 {
+		if tt == nil || tt.Kind() == reflect.String || len(colTypes) == 1 {
 			halign[i] = duit.HalignLeft
 		} else {
 			halign[i] = duit.HalignRight
 		}
 	}
-	gridRows := []*duit.Gridrow{}
+
+	vals := make([]interface{}, len(colTypes))
 	for rows.Next() {
+		row := make([]interface{}, len(colTypes))
+		for i := range row {
+			vals[i] = &row[i]
+		}
 		err = rows.Scan(vals...)
 		lcheck(err, "scanning row")
-		l := make([]string, len(vals))
-		for i, v := range vals {
-			vv := reflect.ValueOf(v)
-			if vv.IsNil() || vv.Elem().IsNil() {
-				l[i] = "NULL"
-			} else {
-				v := vv.Elem().Elem().Interface()
-				// log.Printf("value %#v, %T\n", v, v)
-				if vv, ok := v.([]byte); ok {
-					v = string(vv)
-					if isBinary[i] {
-						v = fmt.Sprintf("%x", v)
+		values = append(values, row)
+	}
+	err = rows.Err()
+	lcheck(err, "reading next row")
+	stats.fetch = time.Since(start)
+	return
+}
+
+// loadDone marks the end of a load, starting a reload requested meanwhile.
+// Called from main loop.
+func (ui *resultUI) loadDone() {
+	ui.loading = false
+	ui.cancelLoad = nil
+	if ui.refreshBut != nil {
+		ui.refreshBut.Text = "refresh"
+	}
+	if ui.pending {
+		ui.pending = false
+		ui.reload()
+	}
+}
+
+// keyColumns returns the indices of the primary key columns in columns, or nil if the key is unknown or not fully selected.
+func keyColumns(columns []resultColumn, primaryKey []string) []int {
+	var keys []int
+	for _, name := range primaryKey {
+		i := -1
+		for j, c := range columns {
+			if c.name == name {
+				i = j
+				break
+			}
+		}
+		if i < 0 {
+			return nil
+		}
+		keys = append(keys, i)
+	}
+	return keys
+}
+
+// rowKey returns the formatted primary key values of a row, to find the row again after a refresh.
+func rowKey(texts []string, keys []int) string {
+	l := make([]string, len(keys))
+	for i, k := range keys {
+		l[i] = texts[k]
+	}
+	return strings.Join(l, "\x00")
+}
+
+// sameColumns returns whether a and b have the same column names and types, so a view on a can be kept for b.
+func sameColumns(a, b []resultColumn) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// reload executes the query again, keeping the current view of the result.
+// Called from main loop.
+func (ui *resultUI) reload() {
+	if ui.loading {
+		ui.pending = true
+		return
+	}
+	ui.loading = true
+	go ui.load(ui.compute)
+}
+
+// setFilter shows only the rows containing text.
+// Called from main loop.
+func (ui *resultUI) setFilter(text string) {
+	ui.filterText = text
+	if ui.filter != nil && ui.grid != nil {
+		ui.filter.Text = text
+		ui.refresh()
+	}
+}
+
+// visible returns whether the result is currently shown.
+func (ui *resultUI) visible() bool {
+	cUI := ui.dbUI.connUI
+	if cUI.db == nil || len(cUI.databaseBox.Kids) != 1 || cUI.databaseBox.Kids[0].UI != ui.dbUI || ui.dbUI.contentUI == nil || len(ui.dbUI.contentUI.Kids) != 1 {
+		return false
+	}
+	switch c := ui.dbUI.contentUI.Kids[0].UI.(type) {
+	case *tableUI:
+		return c.resultUI == ui && c.tabsUI.Buttongroup.Selected == 0
+	case *viewUI:
+		return c.resultUI == ui && c.tabsUI.Buttongroup.Selected == 0
+	case *editUI:
+		return len(c.resultBox.Kids) == 1 && c.resultBox.Kids[0].UI == ui
+	case *resultUI:
+		return c == ui
+	}
+	return false
+}
+
+// startTicker (re)starts auto-refreshing at the interval.
+// Called from main loop.
+func (ui *resultUI) startTicker() {
+	if ui.stop != nil {
+		close(ui.stop)
+		ui.stop = nil
+	}
+	secs, err := strconv.Atoi(ui.interval.Text)
+	if err != nil || secs <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	ui.stop = stop
+	go func() {
+		ticker := time.NewTicker(time.Duration(secs) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				dui.Call <- func() {
+					if ui.stop != stop {
+						return
+					}
+					if !ui.visible() {
+						// stop refreshing when navigating away, showing the data again does not restart it
+						close(ui.stop)
+						ui.stop = nil
+						ui.interval.Text = ""
+						ui.layout()
+						return
 					}
+					ui.reload()
 				}
-				l[i] = fmt.Sprintf("%v", v)
 			}
 		}
-		gridRow := &duit.Gridrow{
-			Values: l,
+	}()
+}
+
+// visibleColumns returns the indices of the columns currently shown in the grid, taking shifting and freezing into account.
+func (ui *resultUI) visibleColumns() []int {
+	l := ui.display
+	if len(l) == 0 {
+		return l
+	}
+	if ui.frozen {
+		offset := ui.offset
+		if offset > len(l)-2 {
+			offset = len(l) - 2
+		}
+		if offset < 0 {
+			offset = 0
 		}
-		gridRows = append(gridRows, gridRow)
+		return append([]int{l[0]}, l[1+offset:]...)
 	}
-	err = rows.Err()
-	lcheck(err, "reading next row")
+	offset := ui.offset
+	if offset > len(l)-1 {
+		offset = len(l) - 1
+	}
+	return l[offset:]
+}
 
-	dui.Call <- func() {
-		if len(gridRows) == 0 {
-			ui.status(fmt.Sprintf("empty resultset"))
-			return
+// shift moves the displayed columns to the left or right by delta.
+// Called from main loop.
+func (ui *resultUI) shift(delta int) {
+	ui.offset += delta
+	max := len(ui.display) - 1
+	if ui.frozen {
+		max--
+	}
+	if ui.offset > max {
+		ui.offset = max
+	}
+	if ui.offset < 0 {
+		ui.offset = 0
+	}
+	ui.refresh()
+}
+
+// refresh filters, sorts and projects the rows onto the visible columns.
+// Selection of rows is kept.
+// Called from main loop.
+func (ui *resultUI) refresh() {
+	defer ui.layout()
+
+	cols := ui.visibleColumns()
+	if len(cols) == 0 {
+		// a grid needs at least one column, show the first even if hidden
+		cols = []int{0}
+	}
+	filter := ""
+	if ui.filter != nil {
+		filter = strings.ToLower(ui.filter.Text)
+	}
+
+	rows := []*duit.Gridrow{}
+	for _, row := range ui.rows {
+		texts := ui.texts[row.Value.(int)]
+		if filter != "" && !rowMatches(texts, ui.display, filter) {
+			continue
+		}
+		l := make([]string, len(cols))
+		for i, col := range cols {
+			l[i] = texts[col]
 		}
+		row.Values = l
+		rows = append(rows, row)
+	}
+	if ui.sortCol >= 0 {
+		kind := ui.columns[ui.sortCol].kind
+		sort.SliceStable(rows, func(i, j int) bool {
+			a := ui.values[rows[i].Value.(int)][ui.sortCol]
+			b := ui.values[rows[j].Value.(int)][ui.sortCol]
+			if ui.sortDesc {
+				a, b = b, a
+			}
+			return compareValues(kind, a, b) < 0
+		})
+	}
 
-		ui.grid = &duit.Gridlist{
-			Header:   &duit.Gridrow{Values: colNames},
-			Rows:     gridRows,
-			Halign:   halign,
-			Multiple: true,
-			Striped:  true,
-			Padding:  duit.SpaceXY(4, 4),
+	header := make([]string, len(cols))
+	halign := make([]duit.Halign, len(cols))
+	for i, col := range cols {
+		header[i] = ui.columns[col].name
+		if col == ui.sortCol {
+			if ui.sortDesc {
+				header[i] += " v"
+			} else {
+				header[i] += " ^"
+			}
 		}
-		ui.Box.Kids = duit.NewKids(duit.NewScroll(ui.grid))
-		ui.layout()
+		halign[i] = ui.halign[col]
+		if len(cols) == 1 {
+			halign[i] = duit.HalignLeft
+		}
+	}
+	ui.grid.Header.Values = header
+	ui.grid.Halign = halign
+	ui.grid.Rows = rows
+	if ui.split != nil {
+		switch ui.split.Kids[0].UI {
+		case ui.chartUI:
+			ui.chartUI.plot()
+		case ui.pivotUI:
+			ui.pivotUI.pivot()
+		}
+	}
+}
+
+// rowMatches returns whether any of the columns cols contains filter, which must be lower case.
+func rowMatches(texts []string, cols []int, filter string) bool {
+	for _, col := range cols {
+		if strings.Contains(strings.ToLower(texts[col]), filter) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ui *resultUI) splitDimensions(width int) []int {
+	if ui.valueUI == nil || !ui.valueUI.open {
+		return []int{width, 0}
+	}
+	second := dui.Scale(350)
+	if second > width/2 {
+		second = width / 2
+	}
+	return []int{width - second, second}
+}
+
+// selectionChanged opens the value inspector for a single selected row, and closes it otherwise.
+// Called from main loop.
+func (ui *resultUI) selectionChanged() {
+	sel := ui.grid.Selected()
+	if len(sel) == 1 {
+		ui.valueUI.show(ui.values[ui.grid.Rows[sel[0]].Value.(int)])
+	} else {
+		ui.valueUI.close()
+	}
+}
+
+// ensureSplit resizes the split after the value inspector opened or closed.
+func (ui *resultUI) ensureSplit() {
+	for _, k := range ui.Box.Kids {
+		if k.UI == ui.split {
+			ui.split.Dimensions(dui, ui.splitDimensions(k.R.Dx()))
+			ui.layout()
+		}
+	}
+}
+
+// resultStats are facts about the execution of a query and fetching its rows.
+type resultStats struct {
+	rows  int
+	exec  time.Duration // until the first response
+	fetch time.Duration // reading all rows
+	bytes int64         // approximate size of the values
+}
+
+func (s resultStats) String() string {
+	return fmt.Sprintf("%d rows, executed in %s, fetched in %s, %s", s.rows, s.exec.Round(time.Millisecond), s.fetch.Round(time.Millisecond), formatBytes(s.bytes))
+}
+
+// valueSize returns the approximate size in bytes of raw value v.
+func valueSize(v interface{}) int64 {
+	switch vv := v.(type) {
+	case nil:
+		return 0
+	case []byte:
+		return int64(len(vv))
+	case string:
+		return int64(len(vv))
+	case bool:
+		return 1
+	}
+	return 8
+}
+
+// formatBytes returns n in a human-readable unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d bytes", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
 	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
 }
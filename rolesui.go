@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/mjl-/duit"
+	"github.com/mjl-/filterlist"
+)
+
+// rolesUI lists the roles/users of the server of a connection, with their attributes and memberships.
+type rolesUI struct {
+	connUI  *connUI
+	message *duit.Label
+	listBox *duit.Box
+
+	duit.Box
+}
+
+func newRolesUI(cUI *connUI) (ui *rolesUI) {
+	ui = &rolesUI{connUI: cUI}
+	ui.message = &duit.Label{}
+	ui.listBox = &duit.Box{}
+	refresh := &duit.Button{
+		Text: "refresh",
+		Click: func() (e duit.Event) {
+			ui.load()
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(
+		&duit.Box{
+			Padding: duit.SpaceXY(4, 2),
+			Margin:  image.Pt(4, 2),
+			Valign:  duit.ValignMiddle,
+			Kids:    duit.NewKids(refresh, ui.message),
+		},
+		ui.listBox,
+	)
+	return
+}
+
+// load fetches the roles. Called from main loop.
+func (ui *rolesUI) load() {
+	ui.message.Text = "loading..."
+	dui.MarkLayout(ui)
+	go ui._load()
+}
+
+// called from outside main loop
+func (ui *rolesUI) _load() {
+	lcheck, handle := errorHandler(func(err error) {
+		dui.Call <- func() {
+			ui.message.Text = fmt.Sprintf("error: %s", err)
+			dui.MarkLayout(ui)
+		}
+	})
+	defer handle()
+
+	// returns name, attributes and roles the role is a member of
+	var q string
+	switch ui.connUI.config.Type {
+	case "postgres":
+		q = `
+			select
+				r.rolname,
+				concat_ws(', ',
+					case when r.rolsuper then 'superuser' end,
+					case when r.rolcanlogin then 'login' end,
+					case when r.rolcreatedb then 'createdb' end,
+					case when r.rolcreaterole then 'createrole' end,
+					case when r.rolreplication then 'replication' end,
+					case when r.rolbypassrls then 'bypassrls' end,
+					case when r.rolconnlimit >= 0 then 'connection limit ' || r.rolconnlimit end,
+					case when r.rolvaliduntil is not null then 'valid until ' || r.rolvaliduntil end
+				),
+				(select string_agg(b.rolname, ', ' order by b.rolname) from pg_auth_members m join pg_roles b on m.roleid = b.oid where m.member = r.oid)
+			from pg_roles r
+			where r.rolname not like 'pg\_%'
+			order by r.rolname
+		`
+	case "mysql":
+		q = `
+			select
+				concat(quote(u.user), '@', quote(u.host)),
+				concat_ws(', ',
+					if(u.super_priv = 'Y', 'super', null),
+					if(u.grant_priv = 'Y', 'grant option', null),
+					if(u.create_user_priv = 'Y', 'create user', null),
+					if(u.account_locked = 'Y', 'locked', null),
+					if(u.password_expired = 'Y', 'password expired', null)
+				),
+				(select group_concat(concat(quote(e.from_user), '@', quote(e.from_host)) separator ', ') from mysql.role_edges e where e.to_user = u.user and e.to_host = u.host)
+			from mysql.user u
+			order by u.user, u.host
+		`
+	case "sqlserver":
+		q = `
+			select
+				p.name,
+				concat(lower(p.type_desc), case when p.is_disabled = 1 then ', disabled' end),
+				stuff((
+					select concat(', ', r.name)
+					from sys.server_role_members m
+					join sys.server_principals r on m.role_principal_id = r.principal_id
+					where m.member_principal_id = p.principal_id
+					for xml path('')
+				), 1, 2, '')
+			from sys.server_principals p
+			where p.type in ('S', 'U', 'G', 'R') and p.name not like '##%'
+			order by p.name
+		`
+	default:
+		panic("bad connection type")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	rows, err := ui.connUI.db.QueryContext(ctx, q)
+	lcheck(err, "listing roles")
+	defer rows.Close()
+	var gridRows []*duit.Gridrow
+	for rows.Next() {
+		var name, attributes, memberOf sql.NullString
+		err = rows.Scan(&name, &attributes, &memberOf)
+		lcheck(err, "scanning row")
+		gridRows = append(gridRows, &duit.Gridrow{
+			Values: []string{name.String, attributes.String, memberOf.String},
+		})
+	}
+	lcheck(rows.Err(), "reading row")
+
+	dui.Call <- func() {
+		gridlist := &duit.Gridlist{
+			Header:  &duit.Gridrow{Values: []string{"name", "attributes", "member of"}},
+			Rows:    gridRows,
+			Striped: true,
+			Padding: duit.SpaceXY(4, 2),
+		}
+		ui.listBox.Kids = duit.NewKids(filterlist.NewFiltergridlist(dui, gridlist))
+		ui.message.Text = fmt.Sprintf("%d roles", len(gridRows))
+		dui.MarkLayout(ui)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// splitStatements splits a SQL script into statements, separated by semicolons, or by lines with just "go" for sqlserver.
+// Semicolons in string literals, quoted identifiers, comments and postgres dollar-quoted strings do not separate statements.
+// Empty statements are skipped.
+func splitStatements(connType, script string) []string {
+	var statements []string
+	add := func(s string) {
+		s = strings.TrimSpace(s)
+		if s != "" && strings.TrimSpace(stripComments(s)) != "" {
+			statements = append(statements, s)
+		}
+	}
+	start := 0
+	lineStart := true
+	for i := 0; i < len(script); {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || (c == '`' && connType == "mysql") || (c == '[' && connType == "sqlserver"):
+			end := c
+			if c == '[' {
+				end = ']'
+			}
+			i++
+			for i < len(script) {
+				if script[i] == '\\' && connType == "mysql" && c != '`' {
+					i += 2
+					continue
+				}
+				if script[i] == end {
+					// doubled quote is an escaped quote
+					if i+1 < len(script) && script[i+1] == end {
+						i += 2
+						continue
+					}
+					break
+				}
+				i++
+			}
+			i++
+		case c == '-' && strings.HasPrefix(script[i:], "--"):
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += 2 + end + 2
+			}
+		case c == '$' && connType == "postgres":
+			tag := dollarTag(script[i:])
+			if tag == "" {
+				i++
+				break
+			}
+			end := strings.Index(script[i+len(tag):], tag)
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += len(tag) + end + len(tag)
+			}
+		case c == ';':
+			add(script[start:i])
+			i++
+			start = i
+		case lineStart && connType == "sqlserver" && isGoLine(script[i:]):
+			add(script[start:i])
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			start = i
+		default:
+			i++
+		}
+		lineStart = i > 0 && i <= len(script) && script[i-1] == '\n'
+	}
+	if start < len(script) {
+		add(script[start:])
+	}
+	return statements
+}
+
+// dollarTag returns the postgres dollar-quote tag at the start of s, like "$$" or "$body$", or empty if none.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$':
+			return s[:i+1]
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9':
+		default:
+			return ""
+		}
+	}
+	return ""
+}
+
+// isGoLine returns whether s starts with a line holding only the sqlserver batch separator "go".
+func isGoLine(s string) bool {
+	line := s
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		line = s[:i]
+	}
+	return strings.EqualFold(strings.TrimSpace(line), "go")
+}
+
+// stripComments returns s without lines that are just "--" comments.
+func stripComments(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), "--") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// runScript executes the statements in order.
+// progress is called before each statement. On error, failed is called, and execution stops if it returns false.
+// Returns the number of statements executed successfully.
+// Called from outside main loop.
+func runScript(ctx context.Context, db *sql.DB, statements []string, progress func(index int), failed func(index int, err error) bool) (executed int) {
+	for i, s := range statements {
+		if ctx.Err() != nil {
+			break
+		}
+		progress(i)
+		_, err := db.ExecContext(ctx, s)
+		if err != nil {
+			if !failed(i, err) {
+				break
+			}
+			continue
+		}
+		executed++
+	}
+	return executed
+}
@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"image"
+	"strings"
+	"time"
+
+	"9fans.net/go/draw"
+	"github.com/mjl-/duit"
+)
+
+// maximum number of databases searched at the same time
+const searchConcurrency = 4
+
+// searchResult is an object found by searchUI, with the column that matched, if any.
+type searchResult struct {
+	dbName string
+	object dbObject
+	column string
+}
+
+// searchUI finds tables, views, columns and routines by name in all databases of a connection.
+type searchUI struct {
+	connUI  *connUI
+	pattern *duit.Field
+	message *duit.Label
+	grid    *duit.Gridlist
+	cancel  context.CancelFunc // cancels the running search, nil if none
+	gen     int                // incremented for each search, results of older searches are ignored
+
+	duit.Box
+}
+
+func newSearchUI(cUI *connUI) (ui *searchUI) {
+	ui = &searchUI{connUI: cUI}
+	ui.message = &duit.Label{}
+	ui.pattern = &duit.Field{
+		Placeholder: "name, or like-pattern...",
+		Keys: func(k rune, m draw.Mouse) (e duit.Event) {
+			if k == '\n' {
+				e.Consumed = true
+				ui.search()
+			}
+			return
+		},
+	}
+	ui.grid = &duit.Gridlist{
+		Header:  &duit.Gridrow{Values: []string{"database", "kind", "object", "column"}},
+		Striped: true,
+		Padding: duit.SpaceXY(4, 2),
+		Changed: func(index int) (e duit.Event) {
+			row := ui.grid.Rows[index]
+			if row.Selected {
+				r := row.Value.(searchResult)
+				ui.connUI.openObject(r.dbName, r.object.Kind, r.object.Name)
+			}
+			return
+		},
+	}
+	search := &duit.Button{
+		Text:     "search",
+		Colorset: &dui.Primary,
+		Click: func() (e duit.Event) {
+			ui.search()
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(
+		&duit.Box{
+			Padding: duit.SpaceXY(4, 2),
+			Margin:  image.Pt(4, 2),
+			Valign:  duit.ValignMiddle,
+			Kids:    duit.NewKids(&duit.Box{Width: 250, Kids: duit.NewKids(ui.pattern)}, search, ui.message),
+		},
+		duit.NewScroll(ui.grid),
+	)
+	return
+}
+
+func (ui *searchUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+// search starts searching all databases for the pattern, canceling a running search.
+// Called from main loop.
+func (ui *searchUI) search() {
+	defer ui.layout()
+	if ui.cancel != nil {
+		ui.cancel()
+		ui.cancel = nil
+	}
+	pattern := ui.pattern.Text
+	if pattern == "" {
+		ui.message.Text = "enter a name to search for"
+		return
+	}
+	if !strings.Contains(pattern, "%") {
+		pattern = "%" + pattern + "%"
+	}
+
+	var dbUIs []*dbUI
+	dbs := map[*dbUI]*sql.DB{} // open connections of dbUIs
+	for _, lv := range ui.connUI.databases.Values {
+		dUI := lv.Value.(*dbUI)
+		dbUIs = append(dbUIs, dUI)
+		dbs[dUI] = dUI.db
+	}
+	config := ui.connUI.config
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ui.cancel = cancel
+	ui.gen++
+	gen := ui.gen
+	ui.grid.Rows = nil
+	ui.message.Text = fmt.Sprintf("searching %d databases...", len(dbUIs))
+
+	go func() {
+		defer cancel()
+		sem := make(chan struct{}, searchConcurrency)
+		done := make(chan struct{})
+		var failed []string
+		for _, dUI := range dbUIs {
+			go func(dUI *dbUI, db *sql.DB) {
+				sem <- struct{}{}
+				defer func() {
+					<-sem
+					done <- struct{}{}
+				}()
+				results, err := searchDatabase(ctx, config, dUI.dbName, db, pattern)
+				dui.Call <- func() {
+					if ui.gen != gen {
+						return
+					}
+					if err != nil {
+						failed = append(failed, fmt.Sprintf("%s: %s", dUI.dbName, err))
+					}
+					for _, r := range results {
+						ui.grid.Rows = append(ui.grid.Rows, &duit.Gridrow{
+							Values: []string{r.dbName, objectKindNames[r.object.Kind], r.object.Name, r.column},
+							Value:  r,
+						})
+					}
+					ui.layout()
+				}
+			}(dUI, dbs[dUI])
+		}
+		for range dbUIs {
+			<-done
+		}
+		dui.Call <- func() {
+			if ui.gen != gen {
+				return
+			}
+			ui.cancel = nil
+			msg := fmt.Sprintf("%d results in %d databases", len(ui.grid.Rows), len(dbUIs))
+			if len(failed) > 0 {
+				msg += fmt.Sprintf("; errors: %s", strings.Join(failed, "; "))
+			}
+			ui.message.Text = msg
+			ui.layout()
+		}
+	}()
+}
+
+// searchDatabase returns the objects in database dbName with a name matching pattern.
+// The open connection db is used if not nil, otherwise a temporary connection is made.
+// Called from outside main loop.
+func searchDatabase(ctx context.Context, config connectionConfig, dbName string, db *sql.DB, pattern string) ([]searchResult, error) {
+	if db == nil {
+		var err error
+		db, err = sql.Open(config.Type, config.connectionString(dbName))
+		if err != nil {
+			return nil, err
+		}
+		defer db.Close()
+	}
+
+	// each query returns kind, name and key as listed by dbUI, and the matching column
+	var q string
+	var args []interface{}
+	switch config.Type {
+	case "postgres":
+		q = `
+			select
+				case table_type when 'VIEW' then 'V' else 'T' end,
+				table_schema || '.' || table_name,
+				table_schema || '.' || table_name,
+				''
+			from information_schema.tables
+			where table_name ilike $1 and table_schema not in ('pg_catalog', 'information_schema')
+			union all
+			select
+				case t.table_type when 'VIEW' then 'V' else 'T' end,
+				c.table_schema || '.' || c.table_name,
+				c.table_schema || '.' || c.table_name,
+				c.column_name
+			from information_schema.columns c
+			join information_schema.tables t on c.table_schema = t.table_schema and c.table_name = t.table_name
+			where c.column_name ilike $1 and c.table_schema not in ('pg_catalog', 'information_schema')
+			union all
+			select
+				case when p.prokind = 'p' then 'P' else 'F' end,
+				n.nspname || '.' || p.proname || '(' || pg_get_function_identity_arguments(p.oid) || ')',
+				p.oid::text,
+				''
+			from pg_proc p
+			join pg_namespace n on p.pronamespace = n.oid
+			where p.proname ilike $1 and p.prokind in ('f', 'p') and n.nspname not in ('pg_catalog', 'information_schema')
+			order by 2, 4
+		`
+		args = append(args, pattern)
+	case "mysql":
+		q = `
+			select
+				case when table_type like '%VIEW' then 'V' else 'T' end,
+				table_name,
+				table_name,
+				''
+			from information_schema.tables
+			where table_schema = ? and table_name like ?
+			union all
+			select
+				case when t.table_type like '%VIEW' then 'V' else 'T' end,
+				c.table_name,
+				c.table_name,
+				c.column_name
+			from information_schema.columns c
+			join information_schema.tables t on c.table_schema = t.table_schema and c.table_name = t.table_name
+			where c.table_schema = ? and c.column_name like ?
+			union all
+			select
+				case routine_type when 'PROCEDURE' then 'P' else 'F' end,
+				routine_name,
+				routine_name,
+				''
+			from information_schema.routines
+			where routine_schema = ? and routine_name like ?
+			order by 2, 4
+		`
+		args = append(args, dbName, pattern, dbName, pattern, dbName, pattern)
+	case "sqlserver":
+		q = `
+			select
+				case table_type when 'VIEW' then 'V' else 'T' end,
+				concat(table_schema, '.', table_name),
+				concat(table_schema, '.', table_name),
+				''
+			from information_schema.tables
+			where table_name like @pattern
+			union all
+			select
+				case t.table_type when 'VIEW' then 'V' else 'T' end,
+				concat(c.table_schema, '.', c.table_name),
+				concat(c.table_schema, '.', c.table_name),
+				c.column_name
+			from information_schema.columns c
+			join information_schema.tables t on c.table_schema = t.table_schema and c.table_name = t.table_name
+			where c.column_name like @pattern
+			union all
+			select
+				case o.type when 'P' then 'P' else 'F' end,
+				concat(schema_name(o.schema_id), '.', o.name),
+				cast(o.object_id as varchar(20)),
+				''
+			from sys.objects o
+			where o.type in ('P', 'FN', 'IF', 'TF') and o.is_ms_shipped = 0 and o.name like @pattern
+			order by 2, 4
+		`
+		args = append(args, sql.Named("pattern", pattern))
+	default:
+		panic("bad connection type")
+	}
+
+	rows, err := db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var results []searchResult
+	for rows.Next() {
+		r := searchResult{dbName: dbName}
+		err = rows.Scan(&r.object.Kind, &r.object.Name, &r.object.Key, &r.column)
+		if err != nil {
+			return nil, fmt.Errorf("scanning row: %s", err)
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"image"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/mjl-/duit"
+	"github.com/mjl-/filterlist"
+)
+
+// serverSetting is a configuration parameter of a server, also stored in snapshots.
+type serverSetting struct {
+	Name    string
+	Value   string
+	Default string
+	Unit    string
+	Source  string
+	Restart bool // whether changing requires a server restart
+}
+
+// serverUI shows the version, uptime and configuration parameters of the server of a connection.
+type serverUI struct {
+	connUI   *connUI
+	settings []serverSetting
+	snapshot map[string]serverSetting // loaded for comparison, nil if not comparing
+
+	info        *duit.Label
+	message     *duit.Label
+	compareWith *duit.Field // name of connection whose snapshot to compare with
+	onlyDiff    *duit.Checkbox
+	listBox     *duit.Box
+
+	duit.Box
+}
+
+// snapshotPath returns the file for the snapshot of a connection, with path separators in the name escaped.
+func snapshotPath(connectionName string) string {
+	return fmt.Sprintf("%s/snapshots/%s.json", duit.AppDataDir("duitsql"), url.PathEscape(connectionName))
+}
+
+func newServerUI(cUI *connUI) (ui *serverUI) {
+	ui = &serverUI{connUI: cUI}
+	ui.info = &duit.Label{}
+	ui.message = &duit.Label{}
+	ui.compareWith = &duit.Field{Placeholder: "connection name...", Text: cUI.config.Name}
+	ui.onlyDiff = &duit.Checkbox{
+		Changed: func() (e duit.Event) {
+			ui.show()
+			return
+		},
+	}
+	ui.listBox = &duit.Box{}
+	refresh := &duit.Button{
+		Text: "refresh",
+		Click: func() (e duit.Event) {
+			ui.load()
+			return
+		},
+	}
+	save := &duit.Button{
+		Text: "save snapshot",
+		Click: func() (e duit.Event) {
+			ui.saveSnapshot()
+			return
+		},
+	}
+	compare := &duit.Button{
+		Text: "compare",
+		Click: func() (e duit.Event) {
+			ui.loadSnapshot()
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(
+		&duit.Box{
+			Padding: duit.SpaceXY(4, 2),
+			Margin:  image.Pt(4, 2),
+			Valign:  duit.ValignMiddle,
+			Kids: duit.NewKids(
+				refresh,
+				save,
+				label("compare with snapshot of"),
+				&duit.Box{Width: 150, Kids: duit.NewKids(ui.compareWith)},
+				compare,
+				ui.onlyDiff,
+				label("only differences"),
+				ui.message,
+			),
+		},
+		&duit.Box{
+			Padding: duit.SpaceXY(4, 2),
+			Kids:    duit.NewKids(ui.info),
+		},
+		ui.listBox,
+	)
+	return
+}
+
+func (ui *serverUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+// load fetches version, uptime and settings. Called from main loop.
+func (ui *serverUI) load() {
+	ui.message.Text = "loading..."
+	ui.layout()
+	go ui._load()
+}
+
+// called from outside main loop
+func (ui *serverUI) _load() {
+	lcheck, handle := errorHandler(func(err error) {
+		dui.Call <- func() {
+			ui.message.Text = fmt.Sprintf("error: %s", err)
+			ui.layout()
+		}
+	})
+	defer handle()
+
+	// qInfo returns version and uptime, either as text or in seconds.
+	// qSettings returns name, value, default, unit, source and whether a restart is required.
+	var qInfo string
+	var qSettings []string
+	switch ui.connUI.config.Type {
+	case "postgres":
+		qInfo = `select version(), date_trunc('second', now() - pg_postmaster_start_time())::text`
+		qSettings = []string{`
+			select name, setting, coalesce(boot_val, ''), coalesce(unit, ''), source, context = 'postmaster'
+			from pg_settings
+			order by name
+		`}
+	case "mysql":
+		qInfo = `select version(), variable_value from performance_schema.global_status where variable_name = 'Uptime'`
+		// mysql does not expose units, nor whether changing a variable requires a restart.
+		// the default is only known for variables still at their compiled-in value.
+		qSettings = []string{`
+			select
+				gv.variable_name,
+				gv.variable_value,
+				case when vi.variable_source = 'COMPILED' then gv.variable_value else '' end,
+				'',
+				lower(coalesce(vi.variable_source, 'variable')),
+				false
+			from performance_schema.global_variables gv
+			left join performance_schema.variables_info vi on gv.variable_name = vi.variable_name
+			order by gv.variable_name
+		`,
+			`select variable_name, variable_value, '', '', 'status', false from performance_schema.global_status order by variable_name`,
+		}
+	case "sqlserver":
+		qInfo = `select @@version, datediff(second, sqlserver_start_time, getdate()) from sys.dm_os_sys_info`
+		qSettings = []string{`
+			select
+				name,
+				cast(value_in_use as nvarchar(100)),
+				'',
+				'',
+				case when value = value_in_use then '' else concat('configured ', cast(value as nvarchar(100))) end,
+				case when is_dynamic = 1 then 0 else 1 end
+			from sys.configurations
+			order by name
+		`}
+	default:
+		panic("bad connection type")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var version, uptime sql.NullString
+	err := ui.connUI.db.QueryRowContext(ctx, qInfo).Scan(&version, &uptime)
+	lcheck(err, "fetching version")
+	if secs, err := strconv.ParseInt(uptime.String, 10, 64); err == nil {
+		uptime.String = (time.Duration(secs) * time.Second).String()
+	}
+
+	var settings []serverSetting
+	for _, q := range qSettings {
+		func() {
+			rows, err := ui.connUI.db.QueryContext(ctx, q)
+			lcheck(err, "listing settings")
+			defer rows.Close()
+			for rows.Next() {
+				var s serverSetting
+				var value sql.NullString
+				err = rows.Scan(&s.Name, &value, &s.Default, &s.Unit, &s.Source, &s.Restart)
+				lcheck(err, "scanning row")
+				s.Value = value.String
+				settings = append(settings, s)
+			}
+			lcheck(rows.Err(), "reading row")
+		}()
+	}
+
+	dui.Call <- func() {
+		ui.settings = settings
+		ui.info.Text = fmt.Sprintf("%s\nup %s", version.String, uptime.String)
+		if ui.connUI.config.Type == "mysql" {
+			ui.info.Text += "\nmysql does not report units and required restarts, and defaults only for unchanged variables"
+		}
+		ui.message.Text = fmt.Sprintf("%d settings", len(settings))
+		ui.show()
+	}
+}
+
+// show (re)creates the filterable list of settings, comparing with a snapshot if loaded.
+// Called from main loop.
+func (ui *serverUI) show() {
+	defer ui.layout()
+	header := []string{"name", "value", "default", "unit", "source", "restart"}
+	if ui.snapshot != nil {
+		header = append(header, "snapshot")
+	}
+	var rows []*duit.Gridrow
+	for _, s := range ui.settings {
+		restart := ""
+		if s.Restart {
+			restart = "yes"
+		}
+		values := []string{s.Name, s.Value, s.Default, s.Unit, s.Source, restart}
+		if ui.snapshot != nil {
+			o, ok := ui.snapshot[s.Name]
+			if ok && o.Value == s.Value {
+				if ui.onlyDiff.Checked {
+					continue
+				}
+				values = append(values, "")
+			} else if !ok {
+				values = append(values, "<missing>")
+			} else {
+				values = append(values, o.Value)
+			}
+		}
+		rows = append(rows, &duit.Gridrow{Values: values})
+	}
+	if ui.snapshot != nil {
+		// settings only in the snapshot
+		current := map[string]bool{}
+		for _, s := range ui.settings {
+			current[s.Name] = true
+		}
+		var missing []string
+		for name := range ui.snapshot {
+			if !current[name] {
+				missing = append(missing, name)
+			}
+		}
+		sort.Strings(missing)
+		for _, name := range missing {
+			rows = append(rows, &duit.Gridrow{Values: []string{name, "<missing>", "", "", "", "", ui.snapshot[name].Value}})
+		}
+	}
+	gridlist := &duit.Gridlist{
+		Header:  &duit.Gridrow{Values: header},
+		Rows:    rows,
+		Striped: true,
+		Padding: duit.SpaceXY(4, 2),
+	}
+	ui.listBox.Kids = duit.NewKids(filterlist.NewFiltergridlist(dui, gridlist))
+}
+
+// saveSnapshot stores the current settings under the connection name.
+// Called from main loop.
+func (ui *serverUI) saveSnapshot() {
+	defer ui.layout()
+	if ui.settings == nil {
+		ui.message.Text = "no settings loaded, nothing to save"
+		return
+	}
+	p := snapshotPath(ui.connUI.config.Name)
+	os.MkdirAll(path.Dir(p), 0777)
+	f, err := os.Create(p)
+	if err == nil {
+		err = json.NewEncoder(f).Encode(ui.settings)
+		err2 := f.Close()
+		if err == nil {
+			err = err2
+		}
+	}
+	if err != nil {
+		ui.message.Text = fmt.Sprintf("saving snapshot: %s", err)
+	} else {
+		ui.message.Text = fmt.Sprintf("saved snapshot of %d settings", len(ui.settings))
+	}
+}
+
+// loadSnapshot reads the snapshot of the connection named in the compare field, and shows the differences.
+// Called from main loop.
+func (ui *serverUI) loadSnapshot() {
+	defer ui.layout()
+	f, err := os.Open(snapshotPath(ui.compareWith.Text))
+	if err != nil {
+		ui.message.Text = fmt.Sprintf("opening snapshot: %s", err)
+		return
+	}
+	defer f.Close()
+	var settings []serverSetting
+	err = json.NewDecoder(f).Decode(&settings)
+	if err != nil {
+		ui.message.Text = fmt.Sprintf("parsing snapshot: %s", err)
+		return
+	}
+	ui.snapshot = map[string]serverSetting{}
+	for _, s := range settings {
+		ui.snapshot[s.Name] = s
+	}
+	ui.message.Text = fmt.Sprintf("comparing with snapshot of %q", ui.compareWith.Text)
+	ui.show()
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"image"
+	"strconv"
+	"time"
+
+	"github.com/mjl-/duit"
+)
+
+// sessionsUI lists the sessions on the server of a connection, with actions to cancel their query or terminate them.
+type sessionsUI struct {
+	connUI   *connUI
+	loading  bool
+	selectID string // session to select after loading
+
+	interval *duit.Field   // auto-refresh interval in seconds, empty or 0 disables
+	stop     chan struct{} // closed to stop auto-refresh, nil if not running
+	message  *duit.Label
+	confirm  *duit.Box // holds confirmation for cancel/terminate
+	grid     *duit.Gridlist
+	gridBox  *duit.Box
+
+	duit.Box
+}
+
+func newSessionsUI(cUI *connUI) (ui *sessionsUI) {
+	ui = &sessionsUI{connUI: cUI}
+	ui.interval = &duit.Field{
+		Text:        "5",
+		Placeholder: "seconds...",
+		Changed: func(text string) (e duit.Event) {
+			ui.startTicker()
+			return
+		},
+	}
+	ui.message = &duit.Label{}
+	ui.confirm = &duit.Box{Margin: image.Pt(4, 2)}
+	ui.gridBox = &duit.Box{}
+	ui.grid = &duit.Gridlist{
+		Header:  &duit.Gridrow{Values: []string{"id", "user", "database", "state", "duration", "waiting", "query"}},
+		Halign:  []duit.Halign{duit.HalignRight, duit.HalignLeft, duit.HalignLeft, duit.HalignLeft, duit.HalignRight, duit.HalignLeft, duit.HalignLeft},
+		Striped: true,
+		Padding: duit.SpaceXY(4, 2),
+		Changed: func(index int) (e duit.Event) {
+			ui.confirm.Kids = nil
+			ui.layout()
+			return
+		},
+	}
+	refresh := &duit.Button{
+		Text: "refresh",
+		Click: func() (e duit.Event) {
+			ui.load()
+			return
+		},
+	}
+	cancelQuery := &duit.Button{
+		Text: "cancel query",
+		Click: func() (e duit.Event) {
+			ui.askConfirm("cancel the query of", "cancel")
+			return
+		},
+	}
+	terminate := &duit.Button{
+		Text:     "terminate session",
+		Colorset: &dui.Danger,
+		Click: func() (e duit.Event) {
+			ui.askConfirm("terminate", "terminate")
+			return
+		},
+	}
+	ui.Box.Kids = duit.NewKids(
+		&duit.Box{
+			Padding: duit.SpaceXY(4, 2),
+			Margin:  image.Pt(4, 2),
+			Valign:  duit.ValignMiddle,
+			Kids:    duit.NewKids(refresh, label("refresh every"), &duit.Box{Width: 40, Kids: duit.NewKids(ui.interval)}, label("seconds"), cancelQuery, terminate, ui.message),
+		},
+		ui.confirm,
+		duit.NewScroll(ui.gridBox),
+	)
+	return
+}
+
+func (ui *sessionsUI) layout() {
+	dui.MarkLayout(ui)
+}
+
+// visible returns whether the sessions UI is currently shown.
+func (ui *sessionsUI) visible() bool {
+	return ui.connUI.db != nil && len(ui.connUI.databaseBox.Kids) == 1 && ui.connUI.databaseBox.Kids[0].UI == ui
+}
+
+// startTicker (re)starts auto-refreshing at the interval.
+// Called from main loop.
+func (ui *sessionsUI) startTicker() {
+	if ui.stop != nil {
+		close(ui.stop)
+		ui.stop = nil
+	}
+	secs, err := strconv.Atoi(ui.interval.Text)
+	if err != nil || secs <= 0 {
+		return
+	}
+	stop := make(chan struct{})
+	ui.stop = stop
+	go func() {
+		ticker := time.NewTicker(time.Duration(secs) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				dui.Call <- func() {
+					if ui.stop != stop {
+						return
+					}
+					if !ui.visible() {
+						close(ui.stop)
+						ui.stop = nil
+						return
+					}
+					if !ui.loading {
+						ui.load()
+					}
+				}
+			}
+		}
+	}()
+}
+
+// selected returns the id of the selected session, or the empty string.
+func (ui *sessionsUI) selected() string {
+	sel := ui.grid.Selected()
+	if len(sel) != 1 {
+		return ""
+	}
+	return ui.grid.Rows[sel[0]].Values[0]
+}
+
+// askConfirm shows a confirmation before performing action (cancel or terminate) on the selected session.
+// Called from main loop.
+func (ui *sessionsUI) askConfirm(what, action string) {
+	defer ui.layout()
+	id := ui.selected()
+	if id == "" {
+		ui.message.Text = "select a session first"
+		return
+	}
+	ui.confirm.Kids = duit.NewKids(
+		label(fmt.Sprintf("%s session %s?", what, id)),
+		&duit.Button{
+			Text:     "yes, " + action,
+			Colorset: &dui.Danger,
+			Click: func() (e duit.Event) {
+				ui.confirm.Kids = nil
+				ui.layout()
+				go ui.act(action, id)
+				return
+			},
+		},
+		&duit.Button{
+			Text: "no",
+			Click: func() (e duit.Event) {
+				ui.confirm.Kids = nil
+				ui.layout()
+				return
+			},
+		},
+	)
+}
+
+// act cancels the query of, or terminates, session id.
+// Called from outside main loop.
+func (ui *sessionsUI) act(action, id string) {
+	lcheck, handle := errorHandler(func(err error) {
+		dui.Call <- func() {
+			ui.message.Text = fmt.Sprintf("error: %s", err)
+			ui.layout()
+		}
+	})
+	defer handle()
+
+	n, err := strconv.ParseInt(id, 10, 64)
+	lcheck(err, "parsing session id")
+
+	var q string
+	switch ui.connUI.config.Type {
+	case "postgres":
+		q = fmt.Sprintf("select pg_terminate_backend(%d)", n)
+		if action == "cancel" {
+			q = fmt.Sprintf("select pg_cancel_backend(%d)", n)
+		}
+	case "mysql":
+		q = fmt.Sprintf("kill connection %d", n)
+		if action == "cancel" {
+			q = fmt.Sprintf("kill query %d", n)
+		}
+	case "sqlserver":
+		if action == "cancel" {
+			lcheck(fmt.Errorf("sqlserver cannot cancel the query of another session, terminate it instead"), "cancel")
+		}
+		q = fmt.Sprintf("kill %d", n)
+	default:
+		panic("bad connection type")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	_, err = ui.connUI.db.ExecContext(ctx, q)
+	lcheck(err, action)
+
+	dui.Call <- func() {
+		ui.message.Text = fmt.Sprintf("%s session %s: done", action, id)
+		ui.load()
+	}
+}
+
+// selectSession loads the sessions and selects the session with id.
+// Called from main loop.
+func (ui *sessionsUI) selectSession(id string) {
+	ui.selectID = id
+	ui.load()
+}
+
+// load fetches the sessions. Called from main loop.
+func (ui *sessionsUI) load() {
+	if ui.stop == nil {
+		ui.startTicker()
+	}
+	if ui.selectID == "" {
+		ui.selectID = ui.selected()
+	}
+	ui.loading = true
+	go ui._load()
+}
+
+// called from outside main loop
+func (ui *sessionsUI) _load() {
+	lcheck, handle := errorHandler(func(err error) {
+		dui.Call <- func() {
+			ui.loading = false
+			ui.message.Text = fmt.Sprintf("error: %s", err)
+			ui.layout()
+		}
+	})
+	defer handle()
+
+	var q string
+	switch ui.connUI.config.Type {
+	case "postgres":
+		q = `
+			select
+				pid,
+				usename,
+				datname,
+				state,
+				extract(epoch from now() - coalesce(query_start, backend_start))::bigint,
+				wait_event_type || ': ' || wait_event,
+				query
+			from pg_stat_activity
+			where pid <> pg_backend_pid()
+			order by query_start asc nulls last
+		`
+	case "mysql":
+		q = `
+			select
+				id,
+				user,
+				db,
+				concat(command, ' ', coalesce(state, '')),
+				time,
+				case when state like 'Waiting%' then state else null end,
+				info
+			from information_schema.processlist
+			where id <> connection_id()
+			order by time desc
+		`
+	case "sqlserver":
+		q = `
+			select
+				s.session_id,
+				s.login_name,
+				db_name(coalesce(r.database_id, s.database_id)),
+				coalesce(r.status, s.status),
+				datediff(second, coalesce(r.start_time, s.last_request_start_time), getdate()),
+				case when r.blocking_session_id > 0 then concat(r.wait_type, ' (blocked by ', r.blocking_session_id, ')') else r.wait_type end,
+				t.text
+			from sys.dm_exec_sessions s
+			left join sys.dm_exec_requests r on s.session_id = r.session_id
+			outer apply sys.dm_exec_sql_text(r.sql_handle) t
+			where s.is_user_process = 1 and s.session_id <> @@spid
+			order by s.session_id
+		`
+	default:
+		panic("bad connection type")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	rows, err := ui.connUI.db.QueryContext(ctx, q)
+	lcheck(err, "listing sessions")
+	defer rows.Close()
+	var gridRows []*duit.Gridrow
+	for rows.Next() {
+		var id, user, database, state, duration, waiting, query sql.NullString
+		err = rows.Scan(&id, &user, &database, &state, &duration, &waiting, &query)
+		lcheck(err, "scanning row")
+		if duration.Valid {
+			duration.String += "s"
+		}
+		gridRows = append(gridRows, &duit.Gridrow{
+			Values: []string{id.String, user.String, database.String, state.String, duration.String, waiting.String, oneLine(query.String)},
+		})
+	}
+	lcheck(rows.Err(), "reading row")
+
+	dui.Call <- func() {
+		defer ui.layout()
+		ui.loading = false
+		for _, row := range gridRows {
+			row.Selected = row.Values[0] == ui.selectID
+		}
+		ui.selectID = ""
+		ui.grid.Rows = gridRows
+		if len(gridRows) == 0 {
+			ui.gridBox.Kids = duit.NewKids(middle(label("no other sessions")))
+		} else {
+			ui.gridBox.Kids = duit.NewKids(ui.grid)
+		}
+		ui.message.Text = fmt.Sprintf("%d sessions, refreshed at %s", len(gridRows), time.Now().Format("15:04:05"))
+	}
+}
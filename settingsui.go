@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"image"
 	"strconv"
+	"time"
 
 	"github.com/mjl-/duit"
 )
@@ -13,6 +14,8 @@ type settingsUI struct {
 	name, host, port, user, password, database *duit.Field
 	tls                                        *duit.Checkbox
 
+	timeFormat, timeZone, precision, boolTrue, boolFalse, null *duit.Field
+
 	duit.Box
 }
 
@@ -21,6 +24,7 @@ func (ui *settingsUI) connectionConfig() connectionConfig {
 	if ui.port.Text != "" {
 		port, _ = strconv.ParseInt(ui.port.Text, 10, 16)
 	}
+	precision, _ := strconv.Atoi(ui.precision.Text)
 	return connectionConfig{
 		Type:     ui.typePostgres.Group.Selected().Value.(string),
 		Name:     ui.name.Text,
@@ -29,6 +33,14 @@ func (ui *settingsUI) connectionConfig() connectionConfig {
 		User:     ui.user.Text,
 		Password: ui.password.Text,
 		Database: ui.database.Text,
+		Format: formatConfig{
+			TimeFormat: ui.timeFormat.Text,
+			TimeZone:   ui.timeZone.Text,
+			Precision:  precision,
+			True:       ui.boolTrue.Text,
+			False:      ui.boolFalse.Text,
+			Null:       ui.null.Text,
+		},
 	}
 }
 
@@ -52,6 +64,16 @@ func newSettingsUI(c connectionConfig, isNew bool, done func()) (ui *settingsUI)
 	ui.password = &duit.Field{Placeholder: "password...", Password: true, Text: c.Password}
 	ui.database = &duit.Field{Placeholder: "database (optional)", Text: c.Database}
 	ui.tls = &duit.Checkbox{Checked: c.TLS}
+	precision := ""
+	if c.Format.Precision > 0 {
+		precision = fmt.Sprintf("%d", c.Format.Precision)
+	}
+	ui.timeFormat = &duit.Field{Placeholder: defaultTimeFormat, Text: c.Format.TimeFormat}
+	ui.timeZone = &duit.Field{Placeholder: "time zone, eg UTC or Local...", Text: c.Format.TimeZone}
+	ui.precision = &duit.Field{Placeholder: "digits after decimal point...", Text: precision}
+	ui.boolTrue = &duit.Field{Placeholder: "true", Text: c.Format.True}
+	ui.boolFalse = &duit.Field{Placeholder: "false", Text: c.Format.False}
+	ui.null = &duit.Field{Placeholder: "NULL", Text: c.Format.Null}
 
 	dbTypes := []*duit.Radiobutton{
 		ui.typePostgres,
@@ -74,9 +96,17 @@ func newSettingsUI(c connectionConfig, isNew bool, done func()) (ui *settingsUI)
 		v, err := strconv.ParseInt(s, 10, 32)
 		return err == nil && v > 0 && v < 64*1024
 	}
+	validTimeZone := func(s string) bool {
+		_, err := time.LoadLocation(s)
+		return err == nil
+	}
+	validPrecision := func(s string) bool {
+		v, err := strconv.ParseInt(s, 10, 32)
+		return err == nil && v >= 0
+	}
 	check := func(_ string) (e duit.Event) {
 		o := primary.Disabled
-		primary.Disabled = ui.name.Text == "" || ui.host.Text == "" || (ui.port.Text != "" && !validPort(ui.port.Text))
+		primary.Disabled = ui.name.Text == "" || ui.host.Text == "" || (ui.port.Text != "" && !validPort(ui.port.Text)) || (ui.timeZone.Text != "" && !validTimeZone(ui.timeZone.Text)) || (ui.precision.Text != "" && !validPrecision(ui.precision.Text))
 		if o != primary.Disabled {
 			dui.MarkDraw(primary)
 		}
@@ -85,6 +115,8 @@ func newSettingsUI(c connectionConfig, isNew bool, done func()) (ui *settingsUI)
 	ui.name.Changed = check
 	ui.host.Changed = check
 	ui.port.Changed = check
+	ui.timeZone.Changed = check
+	ui.precision.Changed = check
 
 	title := "edit connection"
 	action := "save"
@@ -212,6 +244,18 @@ func newSettingsUI(c connectionConfig, isNew bool, done func()) (ui *settingsUI)
 							ui.database,
 							ui.tls,
 							label("require TLS"),
+							label("time format"),
+							ui.timeFormat,
+							label("time zone"),
+							ui.timeZone,
+							label("precision"),
+							ui.precision,
+							label("true"),
+							ui.boolTrue,
+							label("false"),
+							ui.boolFalse,
+							label("null"),
+							ui.null,
 							label(""),
 							actionBox,
 						),
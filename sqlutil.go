@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// quoteIdent quotes a (possibly schema-qualified) identifier for the database type.
+// Names like "schema.table" are quoted per part.
+func quoteIdent(connType, name string) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		switch connType {
+		case "mysql":
+			parts[i] = "`" + strings.Replace(p, "`", "``", -1) + "`"
+		case "sqlserver":
+			parts[i] = "[" + strings.Replace(p, "]", "]]", -1) + "]"
+		default:
+			parts[i] = `"` + strings.Replace(p, `"`, `""`, -1) + `"`
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteString returns s as SQL string literal for the database type.
+func quoteString(connType, s string) string {
+	s = strings.Replace(s, "'", "''", -1)
+	switch connType {
+	case "mysql":
+		// backslash is an escape character in the default sql_mode
+		s = strings.Replace(s, `\`, `\\`, -1)
+	case "sqlserver":
+		return "N'" + s + "'"
+	}
+	return "'" + s + "'"
+}
+
+// floatLiteral returns f, formatted as s, as SQL literal.
+// Postgres has quoted literals for NaN and infinity, mysql and sqlserver cannot store them and get NULL.
+func floatLiteral(connType string, f float64, s string) string {
+	if !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return s
+	}
+	if connType != "postgres" {
+		return "NULL"
+	}
+	switch {
+	case math.IsNaN(f):
+		return "'NaN'"
+	case f > 0:
+		return "'Infinity'"
+	}
+	return "'-Infinity'"
+}
+
+// sqlLiteral returns raw value v of a column of kind as SQL literal for the database type.
+func sqlLiteral(connType string, kind valueKind, v interface{}) string {
+	switch vv := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if connType == "sqlserver" {
+			if vv {
+				return "1"
+			}
+			return "0"
+		}
+		if vv {
+			return "true"
+		}
+		return "false"
+	case int64:
+		return fmt.Sprintf("%v", v)
+	case float32:
+		return floatLiteral(connType, float64(vv), fmt.Sprintf("%v", vv))
+	case float64:
+		return floatLiteral(connType, vv, fmt.Sprintf("%v", vv))
+	case time.Time:
+		layout := "2006-01-02 15:04:05.999999999-07:00"
+		switch connType {
+		case "mysql":
+			layout = "2006-01-02 15:04:05.999999"
+		case "sqlserver":
+			layout = "2006-01-02 15:04:05.999" // datetime has millisecond precision
+		}
+		return quoteString(connType, vv.Format(layout))
+	case []byte:
+		switch kind {
+		case kindBinary:
+			switch connType {
+			case "postgres":
+				return fmt.Sprintf(`'\x%x'`, vv)
+			case "mysql":
+				return fmt.Sprintf("X'%x'", vv)
+			case "sqlserver":
+				return fmt.Sprintf("0x%x", vv)
+			}
+		case kindGUID:
+			return quoteString(connType, formatGUID(vv))
+		case kindNumeric:
+			if n := string(vv); n == "NaN" || strings.HasSuffix(n, "Infinity") {
+				// postgres numeric
+				return quoteString(connType, n)
+			}
+			return string(vv)
+		}
+		return quoteString(connType, string(vv))
+	case string:
+		if kind == kindNumeric {
+			return vv
+		}
+		return quoteString(connType, vv)
+	}
+	return quoteString(connType, fmt.Sprintf("%v", v))
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/mjl-/duit"
+)
+
+const summaryTopN = 5
+
+// columnSummary holds statistics about the values of a column in a resultset.
+type columnSummary struct {
+	distinct int
+	nulls    int
+	min, max interface{}
+	mean     string // empty if column has non-numeric values
+	top      []string
+}
+
+// summarizeColumn computes statistics over all fetched rows, values and their texts, for column col.
+func summarizeColumn(columns []resultColumn, values [][]interface{}, texts [][]string, col int) (s columnSummary) {
+	kind := columns[col].kind
+	counts := map[string]int{}
+	sum := new(big.Rat)
+	numeric := true
+	n := 0
+	for i, row := range values {
+		v := row[col]
+		if v == nil {
+			s.nulls++
+			continue
+		}
+		counts[texts[i][col]]++
+		if s.min == nil || compareValues(kind, v, s.min) < 0 {
+			s.min = v
+		}
+		if s.max == nil || compareValues(kind, v, s.max) > 0 {
+			s.max = v
+		}
+		if r, ok := numericValue(kind, v); ok && numeric {
+			sum.Add(sum, r)
+			n++
+		} else {
+			numeric = false
+		}
+	}
+	s.distinct = len(counts)
+	if numeric && n > 0 {
+		s.mean = sum.Quo(sum, new(big.Rat).SetInt64(int64(n))).FloatString(4)
+	}
+
+	type count struct {
+		text string
+		n    int
+	}
+	var l []count
+	for text, n := range counts {
+		l = append(l, count{text, n})
+	}
+	sort.Slice(l, func(i, j int) bool {
+		if l[i].n != l[j].n {
+			return l[i].n > l[j].n
+		}
+		return l[i].text < l[j].text
+	})
+	for i, c := range l {
+		if i >= summaryTopN {
+			break
+		}
+		s.top = append(s.top, fmt.Sprintf("%s (%d)", c.text, c.n))
+	}
+	return
+}
+
+// summaryUI shows a summary per column of the rows of a resultUI.
+type summaryUI struct {
+	resultUI *resultUI
+	box      *duit.Box
+	duit.Box
+}
+
+func newSummaryUI(rUI *resultUI) *summaryUI {
+	ui := &summaryUI{
+		resultUI: rUI,
+		box:      &duit.Box{Height: -1},
+	}
+	ui.Box.Kids = duit.NewKids(&duit.Scroll{Height: -1, Kid: duit.Kid{UI: ui.box}})
+	return ui
+}
+
+// called from main loop
+func (ui *summaryUI) init() {
+	ui.box.Kids = duit.NewKids(middle(label("summarizing...")))
+	dui.MarkLayout(ui)
+
+	rUI := ui.resultUI
+	formatter := newValueFormatter(rUI.dbUI.connUI.config.Format)
+	// the slices are replaced, not modified, when the result is reloaded, so we can summarize outside the main loop
+	columns, values, texts := rUI.columns, rUI.values, rUI.texts
+	go func() {
+		kids := []duit.UI{
+			&duit.Label{Font: bold, Text: "column"},
+			&duit.Label{Font: bold, Text: "type"},
+			&duit.Label{Font: bold, Text: "distinct"},
+			&duit.Label{Font: bold, Text: "nulls"},
+			&duit.Label{Font: bold, Text: "min"},
+			&duit.Label{Font: bold, Text: "max"},
+			&duit.Label{Font: bold, Text: "mean"},
+			&duit.Label{Font: bold, Text: fmt.Sprintf("top %d", summaryTopN)},
+		}
+		for i, c := range columns {
+			s := summarizeColumn(columns, values, texts, i)
+			kids = append(kids,
+				label(c.name),
+				label(c.dbType),
+				label(fmt.Sprintf("%d", s.distinct)),
+				label(fmt.Sprintf("%d", s.nulls)),
+				label(formatter.format(c.kind, s.min)),
+				label(formatter.format(c.kind, s.max)),
+				label(s.mean),
+				label(strings.Join(s.top, "\n")),
+			)
+		}
+		dui.Call <- func() {
+			ui.box.Padding = duit.SpaceXY(duit.ScrollbarSize, 6)
+			ui.box.Margin = image.Pt(0, 6)
+			ui.box.Kids = duit.NewKids(
+				&duit.Label{Font: bold, Text: fmt.Sprintf("summary of %d rows", len(values))},
+				&duit.Grid{
+					Columns: 8,
+					Width:   -1,
+					Padding: duit.NSpaceXY(8, 4, 1),
+					Kids:    duit.NewKids(kids...),
+				},
+			)
+			dui.MarkLayout(ui)
+		}
+	}()
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"image"
+	"strings"
 
 	"github.com/mjl-/duit"
 )
@@ -139,6 +140,142 @@ func (ui *tablestructUI) _load(ctx context.Context, cancelQueryFunc func()) {
 	}
 	lcheck(rows.Err(), "reading row")
 
+	var qPrivileges string
+	switch ui.dbUI.connUI.config.Type {
+	case "postgres":
+		qPrivileges = `
+			select grantee, privilege_type, is_grantable = 'YES'
+			from information_schema.table_privileges
+			where table_schema || '.' || table_name=$1
+			order by grantee, privilege_type
+		`
+	case "mysql":
+		qPrivileges = `
+			select grantee, privilege_type, is_grantable = 'YES'
+			from information_schema.table_privileges
+			where table_schema=? and table_name=?
+			order by grantee, privilege_type
+		`
+	case "sqlserver":
+		qPrivileges = `
+			select grantee, privilege_type, case when is_grantable = 'YES' then 1 else 0 end
+			from information_schema.table_privileges
+			where concat(table_schema, '.', table_name)=@name
+			order by grantee, privilege_type
+		`
+	}
+	var grantees []string
+	privileges := map[string][]string{}
+	grantable := map[string][]string{}
+	rows, err = ui.dbUI.db.QueryContext(ctx, qPrivileges, args...)
+	lcheck(err, "fetching privileges")
+	defer rows.Close()
+	for rows.Next() {
+		var grantee, privilege string
+		var isGrantable bool
+		err = rows.Scan(&grantee, &privilege, &isGrantable)
+		lcheck(err, "scanning row")
+		if _, ok := privileges[grantee]; !ok {
+			grantees = append(grantees, grantee)
+		}
+		privileges[grantee] = append(privileges[grantee], privilege)
+		if isGrantable {
+			grantable[grantee] = append(grantable[grantee], privilege)
+		}
+	}
+	lcheck(rows.Err(), "reading row")
+
+	// returns name, timing and event of the triggers on the table
+	var qTriggers string
+	switch ui.dbUI.connUI.config.Type {
+	case "postgres":
+		qTriggers = `
+			select trigger_name, action_timing, string_agg(event_manipulation, ', ' order by event_manipulation)
+			from information_schema.triggers
+			where event_object_schema || '.' || event_object_table=$1
+			group by trigger_name, action_timing
+			order by trigger_name
+		`
+	case "mysql":
+		qTriggers = `
+			select trigger_name, action_timing, event_manipulation
+			from information_schema.triggers
+			where event_object_schema=? and event_object_table=?
+			order by trigger_name
+		`
+	case "sqlserver":
+		qTriggers = `
+			select
+				t.name,
+				case when t.is_instead_of_trigger = 1 then 'INSTEAD OF' else 'AFTER' end,
+				stuff((
+					select concat(', ', e.type_desc)
+					from sys.trigger_events e
+					where e.object_id = t.object_id
+					for xml path('')
+				), 1, 2, '')
+			from sys.triggers t
+			where concat(object_schema_name(t.parent_id), '.', object_name(t.parent_id))=@name
+			order by t.name
+		`
+	}
+	triggerUIs := []duit.UI{
+		&duit.Label{Font: bold, Text: "name"},
+		&duit.Label{Font: bold, Text: "timing"},
+		&duit.Label{Font: bold, Text: "events"},
+	}
+	rows, err = ui.dbUI.db.QueryContext(ctx, qTriggers, args...)
+	lcheck(err, "fetching triggers")
+	defer rows.Close()
+	for rows.Next() {
+		var name, timing, events sql.NullString
+		err = rows.Scan(&name, &timing, &events)
+		lcheck(err, "scanning row")
+		triggerUIs = append(triggerUIs, label(name.String), label(timing.String), label(events.String))
+	}
+	lcheck(rows.Err(), "reading row")
+
+	connType := ui.dbUI.connUI.config.Type
+	table := quoteIdent(connType, ui.name)
+	privilegeUIs := []duit.UI{
+		&duit.Label{Font: bold, Text: "grantee"},
+		&duit.Label{Font: bold, Text: "privileges"},
+		&duit.Label{Font: bold, Text: "with grant option"},
+	}
+	var statements []string
+	for _, grantee := range grantees {
+		privilegeUIs = append(privilegeUIs,
+			label(grantee),
+			label(strings.Join(privileges[grantee], ", ")),
+			label(strings.Join(grantable[grantee], ", ")),
+		)
+		quotedGrantee := grantee
+		switch {
+		case connType == "mysql":
+			// mysql grantees are already quoted, as 'user'@'host'
+		case strings.EqualFold(grantee, "public"):
+			// keyword for all roles, quoted it would be a role named PUBLIC
+			quotedGrantee = "public"
+		default:
+			quotedGrantee = quoteIdent(connType, grantee)
+		}
+		list := strings.ToLower(strings.Join(privileges[grantee], ", "))
+		statements = append(statements,
+			fmt.Sprintf("grant %s on %s to %s;", list, table, quotedGrantee),
+			fmt.Sprintf("revoke %s on %s from %s;", list, table, quotedGrantee),
+		)
+	}
+	statementsText := strings.Join(statements, "\n")
+	copyStatements := &duit.Button{
+		Text: "copy to editor",
+		Click: func() (e duit.Event) {
+			eUI := ui.dbUI.editUI
+			eUI.edit.Append([]byte("\n" + statementsText + "\n"))
+			ui.dbUI.showEditor()
+			return
+		},
+	}
+
 	columnUIs := []duit.UI{
 		&duit.Label{Font: bold, Text: "name"},
 		&duit.Label{Font: bold, Text: "type"},
@@ -174,6 +311,31 @@ func (ui *tablestructUI) _load(ctx context.Context, cancelQueryFunc func()) {
 				},
 				Kids: duit.NewKids(columnUIs...),
 			},
+			&duit.Label{Font: bold, Text: "triggers"},
+			&duit.Grid{
+				Columns: 3,
+				Width:   -1,
+				Padding: []duit.Space{
+					duit.Space{Top: 1, Right: 4, Bottom: 1, Left: 0},
+					duit.Space{Top: 1, Right: 2, Bottom: 1, Left: 2},
+					duit.Space{Top: 1, Right: 0, Bottom: 1, Left: 4},
+				},
+				Kids: duit.NewKids(triggerUIs...),
+			},
+			&duit.Label{Font: bold, Text: "privileges"},
+			&duit.Grid{
+				Columns: 3,
+				Width:   -1,
+				Padding: []duit.Space{
+					duit.Space{Top: 1, Right: 4, Bottom: 1, Left: 0},
+					duit.Space{Top: 1, Right: 2, Bottom: 1, Left: 2},
+					duit.Space{Top: 1, Right: 0, Bottom: 1, Left: 4},
+				},
+				Kids: duit.NewKids(privilegeUIs...),
+			},
+			&duit.Label{Font: bold, Text: "grant/revoke statements"},
+			label(statementsText),
+			copyStatements,
 		)
 		ui.Box.Kids = duit.NewKids(ui.scroll)
 		ui.layout()
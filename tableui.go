@@ -26,21 +26,34 @@ func (ui *tableUI) init() {
 	}
 	query := `select * from ` + ui.name
 	ui.resultUI = newResultUI(ui.dbUI, query)
+	ui.resultUI.table = ui.name
 	tsUI := newTableStructUI(ui.dbUI, ui.name)
 	tsUI.init()
+	iUI := newImportUI(ui.dbUI, ui.name, func() {
+		ui.resultUI.reload()
+	})
+	gUI := newGenerateUI(ui.dbUI, ui.name, func() {
+		ui.resultUI.reload()
+	})
 	ui.tabsUI = &duit.Tabs{
 		Buttongroup: &duit.Buttongroup{
 			Texts: []string{
 				"Data",
 				"Structure",
+				"Import",
+				"Copy",
+				"Generate",
 			},
 		},
 		UIs: []duit.UI{
 			ui.resultUI,
 			tsUI,
+			iUI,
+			newCopyTableUI(ui.dbUI, ui.name),
+			gUI,
 		},
 	}
 	ui.Box.Kids = duit.NewKids(ui.tabsUI)
 	dui.MarkLayout(nil) // xxx
-	go ui.resultUI.load()
+	go ui.resultUI.load(ui.resultUI.compute)
 }
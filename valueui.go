@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mjl-/duit"
+)
+
+// valueUI is the detail pane of a resultUI, showing the full value of a single cell of the selected row.
+type valueUI struct {
+	resultUI *resultUI
+	open     bool
+	row      []interface{}
+	column   int
+
+	columns *duit.List
+	info    *duit.Label
+	fold    *duit.Field // depth at which JSON and XML are folded, empty for no folding
+	path    *duit.Field
+	status  *duit.Label
+	content *duit.Box
+
+	duit.Box
+}
+
+func newValueUI(rUI *resultUI) (ui *valueUI) {
+	ui = &valueUI{resultUI: rUI}
+
+	values := make([]*duit.ListValue, len(rUI.columns))
+	for i, c := range rUI.columns {
+		values[i] = &duit.ListValue{
+			Text:     c.name,
+			Value:    i,
+			Selected: i == 0,
+		}
+	}
+	ui.columns = &duit.List{
+		Values: values,
+		Changed: func(index int) (e duit.Event) {
+			lv := ui.columns.Values[index]
+			if !lv.Selected {
+				lv.Selected = true
+			}
+			ui.column = lv.Value.(int)
+			ui.render()
+			return
+		},
+	}
+	ui.info = &duit.Label{}
+	ui.fold = &duit.Field{
+		Placeholder: "fold depth...",
+		Changed: func(text string) (e duit.Event) {
+			ui.render()
+			return
+		},
+	}
+	ui.path = &duit.Field{Placeholder: "file to save value to..."}
+	save := &duit.Button{
+		Text: "save",
+		Click: func() (e duit.Event) {
+			ui.save()
+			return
+		},
+	}
+	closeButton := &duit.Button{
+		Text: "close",
+		Click: func() (e duit.Event) {
+			for _, row := range ui.resultUI.grid.Rows {
+				row.Selected = false
+			}
+			ui.close()
+			return
+		},
+	}
+	ui.status = &duit.Label{}
+	ui.content = &duit.Box{}
+
+	ui.Box.Kids = duit.NewKids(
+		&duit.Split{
+			Vertical:   true,
+			Gutter:     1,
+			Background: dui.Gutter,
+			Split: func(height int) []int {
+				first := height / 4
+				return []int{first, height - first}
+			},
+			Kids: duit.NewKids(
+				duit.NewScroll(ui.columns),
+				&duit.Box{
+					Kids: duit.NewKids(
+						&duit.Box{
+							Padding: duit.SpaceXY(4, 2),
+							Margin:  image.Pt(4, 2),
+							Kids:    duit.NewKids(closeButton, ui.info, ui.fold, ui.path, save, ui.status),
+						},
+						ui.content,
+					),
+				},
+			),
+		},
+	)
+	return
+}
+
+// show opens the inspector for a row, keeping the currently selected column.
+// Called from main loop.
+func (ui *valueUI) show(row []interface{}) {
+	ui.row = row
+	ui.status.Text = ""
+	if !ui.open {
+		ui.open = true
+		ui.resultUI.ensureSplit()
+	}
+	ui.render()
+}
+
+// close hides the inspector.
+// Called from main loop.
+func (ui *valueUI) close() {
+	if !ui.open {
+		return
+	}
+	ui.open = false
+	ui.row = nil
+	ui.content.Kids = nil
+	ui.resultUI.ensureSplit()
+}
+
+// render shows the value of the selected column in the most suitable form.
+// Called from main loop.
+func (ui *valueUI) render() {
+	defer dui.MarkLayout(ui)
+	if ui.row == nil {
+		return
+	}
+	col := ui.resultUI.columns[ui.column]
+	v := ui.row[ui.column]
+	fold, _ := strconv.Atoi(ui.fold.Text)
+
+	var kind, text string
+	var buf []byte
+	switch vv := v.(type) {
+	case nil:
+		kind = "NULL"
+	case []byte:
+		buf = vv
+	case string:
+		buf = []byte(vv)
+	default:
+		kind = fmt.Sprintf("%T", v)
+		text = fmt.Sprintf("%v", v)
+	}
+
+	var img duit.UI
+	if buf != nil {
+		if s, err := prettyJSON(buf, fold); err == nil {
+			kind, text = "JSON", s
+		} else if s, err := prettyXML(buf, fold); err == nil {
+			kind, text = "XML", s
+		} else if col.kind == kindBinary || !utf8.Valid(buf) {
+			kind, text = "binary", hex.Dump(buf)
+			if _, format, err := image.DecodeConfig(bytes.NewReader(buf)); err == nil {
+				dimg, err := duit.ReadImage(dui.Display, bytes.NewReader(buf))
+				if err == nil {
+					kind = format + " image"
+					img = &duit.Image{Image: dimg}
+				}
+			}
+		} else {
+			kind, text = "text", string(buf)
+		}
+		kind += fmt.Sprintf(", %d bytes", len(buf))
+	}
+	ui.info.Text = fmt.Sprintf("%s (%s): %s", col.name, col.dbType, kind)
+
+	edit := readOnlyEdit(text)
+	if img != nil {
+		ui.content.Kids = duit.NewKids(
+			&duit.Split{
+				Vertical:   true,
+				Gutter:     1,
+				Background: dui.Gutter,
+				Split: func(height int) []int {
+					half := height / 2
+					return []int{half, height - half}
+				},
+				Kids: duit.NewKids(duit.NewScroll(img), edit),
+			},
+		)
+	} else {
+		ui.content.Kids = duit.NewKids(edit)
+	}
+}
+
+// save writes the raw value of the selected cell to the file in the path field.
+// Called from main loop.
+func (ui *valueUI) save() {
+	defer dui.MarkLayout(ui)
+	if ui.row == nil || ui.path.Text == "" {
+		ui.status.Text = "no value or path"
+		return
+	}
+	var buf []byte
+	switch v := ui.row[ui.column].(type) {
+	case nil:
+	case []byte:
+		buf = v
+	case string:
+		buf = []byte(v)
+	default:
+		buf = []byte(fmt.Sprintf("%v", v))
+	}
+	err := ioutil.WriteFile(ui.path.Text, buf, 0666)
+	if err != nil {
+		ui.status.Text = fmt.Sprintf("error: %s", err)
+	} else {
+		ui.status.Text = fmt.Sprintf("saved %d bytes", len(buf))
+	}
+}
+
+// prettyJSON returns buf indented, replacing objects and arrays nested at depth fold or deeper by a placeholder.
+// A fold of 0 means no folding.
+// Object keys keep their order, so the JSON is read as a token stream instead of being unmarshaled.
+func prettyJSON(buf []byte, fold int) (string, error) {
+	buf = bytes.TrimSpace(buf)
+	if len(buf) == 0 || (buf[0] != '{' && buf[0] != '[') || !json.Valid(buf) {
+		return "", fmt.Errorf("not a json object or array")
+	}
+	d := json.NewDecoder(bytes.NewReader(buf))
+	d.UseNumber()
+	var b strings.Builder
+
+	// skip reads the remainder of an object or array, returning the number of elements.
+	var skip func() int
+	skip = func() int {
+		n := 0
+		for {
+			t, _ := d.Token()
+			switch t {
+			case json.Delim('{'), json.Delim('['):
+				skip()
+			case json.Delim('}'), json.Delim(']'):
+				return n
+			}
+			n++
+		}
+	}
+
+	var value func(t json.Token, depth int)
+	value = func(t json.Token, depth int) {
+		indent := strings.Repeat("  ", depth+1)
+		switch t {
+		case json.Delim('{'), json.Delim('['):
+			open := t.(json.Delim)
+			end := "}"
+			if open == '[' {
+				end = "]"
+			}
+			if fold > 0 && depth >= fold {
+				n := skip()
+				if open == '{' {
+					n /= 2
+				}
+				fmt.Fprintf(&b, "%c…%s (%d)", open, end, n)
+				return
+			}
+			b.WriteByte(byte(open))
+			first := true
+			for d.More() {
+				if !first {
+					b.WriteString(",")
+				}
+				first = false
+				b.WriteString("\n" + indent)
+				t, _ := d.Token()
+				if open == '{' {
+					k, _ := json.Marshal(t)
+					b.Write(k)
+					b.WriteString(": ")
+					t, _ = d.Token()
+				}
+				value(t, depth+1)
+			}
+			d.Token()
+			if !first {
+				b.WriteString("\n" + strings.Repeat("  ", depth))
+			}
+			b.WriteString(end)
+		default:
+			v, _ := json.Marshal(t)
+			b.Write(v)
+		}
+	}
+	t, err := d.Token()
+	if err != nil {
+		return "", err
+	}
+	value(t, 0)
+	return b.String(), nil
+}
+
+// prettyXML returns buf indented, replacing the contents of elements nested at depth fold or deeper by a comment.
+// A fold of 0 means no folding.
+func prettyXML(buf []byte, fold int) (string, error) {
+	buf = bytes.TrimSpace(buf)
+	if len(buf) == 0 || buf[0] != '<' {
+		return "", fmt.Errorf("not xml")
+	}
+	d := xml.NewDecoder(bytes.NewReader(buf))
+	var b bytes.Buffer
+	e := xml.NewEncoder(&b)
+	e.Indent("", "  ")
+	depth := 0
+	elements := 0
+	for {
+		t, err := d.Token()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return "", err
+		}
+		t = xml.CopyToken(t)
+		folded := fold > 0 && depth > fold
+		switch tt := t.(type) {
+		case xml.StartElement:
+			elements++
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if len(bytes.TrimSpace(tt)) == 0 {
+				continue
+			}
+		case xml.ProcInst:
+			if tt.Target == "xml" {
+				continue
+			}
+		}
+		if folded {
+			continue
+		}
+		if fold > 0 && depth > fold {
+			if _, ok := t.(xml.StartElement); !ok {
+				continue
+			}
+			t = xml.Comment(" … ")
+		}
+		if err := e.EncodeToken(t); err != nil {
+			return "", err
+		}
+	}
+	if elements == 0 {
+		return "", fmt.Errorf("no xml elements")
+	}
+	if err := e.Flush(); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
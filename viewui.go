@@ -43,5 +43,5 @@ func (ui *viewUI) init() {
 	}
 	ui.Box.Kids = duit.NewKids(ui.tabsUI)
 	dui.MarkLayout(nil) // xxx
-	go ui.resultUI.load()
+	go ui.resultUI.load(ui.resultUI.compute)
 }